@@ -0,0 +1,419 @@
+package m3u8
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 属性列表使用的struct tag，格式是`m3u8:"<name>[,<option>...]"`，
+// 没有m3u8 tag的字段会被忽略。选项有：
+//
+//	quoted    值是<quoted-string>，否则是<enumerated-string>等不需要引号的类型
+//	required  必须的属性，解析时没有会返回错误，输出时即使是零值也会输出
+//	none      值是<quoted-string>，但是NONE不需要引号，比如CLOSED-CAPTIONS
+//	prefix    字段是ClientAttributes，按顺序保存所有以<name>开头的属性，比如X-
+//	enum=A|B  值必须是A或者B
+//	prec=N    <decimal-floating-point>输出N位小数
+//	signed    值是<signed-decimal-floating-point>，可以是负数，比如TIME-OFFSET
+//
+// 字段的类型决定了值的格式：
+//
+//	string                  <quoted-string>或<enumerated-string>
+//	int,int64...            <decimal-integer>
+//	float64,*float64        <decimal-floating-point>，*float64可以区分0和没有
+//	bool                    YES/NO
+//	[]byte                  <hexadecimal-sequence>
+//	time.Time               <date-time-msec>，总是有引号
+//	encoding.TextMarshaler  其他类型，比如Resolution
+const structTagName = "m3u8"
+
+var (
+	timeType             = reflect.TypeOf(time.Time{})
+	clientAttributesType = reflect.TypeOf(ClientAttributes(nil))
+	textMarshalerType    = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType  = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	// 缓存解析好的字段，reflect.Type:[]*attributeField
+	attributeFieldCache sync.Map
+)
+
+// struct中一个属性字段的信息
+type attributeField struct {
+	index    int
+	name     string
+	quoted   bool
+	required bool
+	none     bool
+	prefix   bool
+	enums    []string
+	prec     int
+	signed   bool
+}
+
+// 返回t中所有的属性字段
+func attributeFields(t reflect.Type) ([]*attributeField, error) {
+	if v, ok := attributeFieldCache.Load(t); ok {
+		return v.([]*attributeField), nil
+	}
+	var fields []*attributeField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup(structTagName)
+		if !ok || tag == "-" {
+			continue
+		}
+		options := strings.Split(tag, ",")
+		f := &attributeField{index: i, name: options[0], prec: -1}
+		if f.name == "" {
+			return nil, fmt.Errorf("%s.%s missing attribute name", t, sf.Name)
+		}
+		for _, o := range options[1:] {
+			switch {
+			case o == "quoted":
+				f.quoted = true
+			case o == "required":
+				f.required = true
+			case o == "none":
+				f.none = true
+			case o == "signed":
+				f.signed = true
+			case o == "prefix":
+				if sf.Type != clientAttributesType {
+					return nil, fmt.Errorf("%s.%s prefix field must be %s", t, sf.Name, clientAttributesType)
+				}
+				f.prefix = true
+			case strings.HasPrefix(o, "enum="):
+				f.enums = strings.Split(o[len("enum="):], "|")
+			case strings.HasPrefix(o, "prec="):
+				n, err := strconv.Atoi(o[len("prec="):])
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s invalid option '%s'", t, sf.Name, o)
+				}
+				f.prec = n
+			default:
+				return nil, fmt.Errorf("%s.%s unknown option '%s'", t, sf.Name, o)
+			}
+		}
+		fields = append(fields, f)
+	}
+	attributeFieldCache.Store(t, fields)
+	return fields, nil
+}
+
+// 返回v指向的struct
+func attributeStruct(v interface{}, ptr bool) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return rv, fmt.Errorf("m3u8: nil %s", rv.Type())
+		}
+		rv = rv.Elem()
+	} else if ptr {
+		return rv, fmt.Errorf("m3u8: non-pointer %T", v)
+	}
+	if rv.Kind() != reflect.Struct {
+		return rv, fmt.Errorf("m3u8: %T is not a struct", v)
+	}
+	return rv, nil
+}
+
+// 解析<attribute-list>，根据struct tag把值保存到v指向的struct中，不认识的属性会被忽略
+func UnmarshalAttributes(value []byte, v interface{}) error {
+	return unmarshalAttributes(value, v, nil)
+}
+
+// 宽松模式下记录修复的函数，column是在<attribute-list>中的列号，nil表示严格模式
+type warnFunc func(column int, format string, args ...interface{})
+
+// warn不是nil时，尽量修复错误的属性：引号不对的使用原来的值，
+// 大小写不对的枚举值转换成大写，其他错误的可选属性被忽略
+func unmarshalAttributes(value []byte, v interface{}, warn warnFunc) error {
+	rv, err := attributeStruct(v, true)
+	if err != nil {
+		return err
+	}
+	l, err := parseAttributeList(value, warn)
+	if err != nil {
+		return err
+	}
+	fields, err := attributeFields(rv.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fv := rv.Field(f.index)
+		if f.prefix {
+			var attrs ClientAttributes
+			for i := 0; i < len(l); i++ {
+				k := l[i].Name
+				if !strings.HasPrefix(k, f.name) || len(k) <= len(f.name) {
+					continue
+				}
+				a, err := parseClientAttribute(&l[i])
+				if err != nil {
+					if warn == nil {
+						return attributeError(l[i].column, "%v", err)
+					}
+					warn(l[i].column, "%v, ignored", err)
+					continue
+				}
+				attrs = append(attrs, a)
+			}
+			fv.Set(reflect.ValueOf(attrs))
+			continue
+		}
+		i := l.Index(f.name)
+		if i < 0 {
+			if f.required {
+				return attributeError(0, "missing %s", f.name)
+			}
+			continue
+		}
+		a := &l[i]
+		if s := f.checkQuoted(fv.Type(), a); s != "" {
+			if warn == nil {
+				return attributeError(a.column, "%s %s", f.name, s)
+			}
+			warn(a.column, "%s %s", f.name, s)
+		}
+		err = f.checkEnum(a.Value)
+		if err == nil {
+			err = f.setValue(fv, a.Value)
+		}
+		if err == nil {
+			continue
+		}
+		if warn == nil {
+			return attributeError(a.column, "%s %v", f.name, err)
+		}
+		// 小写的YES/NO和枚举值
+		s := strings.ToUpper(a.Value)
+		if s != a.Value && f.checkEnum(s) == nil && f.setValue(fv, s) == nil {
+			warn(a.column, "%s '%s' should be '%s'", f.name, a.Value, s)
+			continue
+		}
+		if f.required {
+			return attributeError(a.column, "%s %v", f.name, err)
+		}
+		warn(a.column, "%s %v, ignored", f.name, err)
+	}
+	return nil
+}
+
+// 根据struct tag把v格式化成<attribute-list>，零值的属性不会输出
+func MarshalAttributes(v interface{}) ([]byte, error) {
+	return appendAttributes(nil, v)
+}
+
+// 把v格式化成<attribute-list>添加到b，v也可以是AttributeList
+func appendAttributes(b []byte, v interface{}) ([]byte, error) {
+	switch l := v.(type) {
+	case AttributeList:
+		return l.Append(b), nil
+	case *AttributeList:
+		return l.Append(b), nil
+	}
+	rv, err := attributeStruct(v, false)
+	if err != nil {
+		return b, err
+	}
+	fields, err := attributeFields(rv.Type())
+	if err != nil {
+		return b, err
+	}
+	first := true
+	for _, f := range fields {
+		fv := rv.Field(f.index)
+		if f.prefix {
+			attrs := fv.Interface().(ClientAttributes)
+			for i := 0; i < len(attrs); i++ {
+				a := &attrs[i]
+				if err := a.check(f.name); err != nil {
+					return b, err
+				}
+				b = appendAttribute(b, first, a.Name, a.Value, a.Type == ClientString)
+				first = false
+			}
+			continue
+		}
+		if fv.IsZero() && !f.required {
+			continue
+		}
+		s, quoted, err := f.formatValue(fv)
+		if err == nil {
+			err = f.checkEnum(s)
+		}
+		if err != nil {
+			return b, fmt.Errorf("%s %v", f.name, err)
+		}
+		b = appendAttribute(b, first, f.name, s, quoted)
+		first = false
+	}
+	return b, nil
+}
+
+// 添加name=value，不是第一个属性，先添加','
+func appendAttribute(b []byte, first bool, name, value string, quoted bool) []byte {
+	if !first {
+		b = append(b, ',')
+	}
+	b = append(b, name...)
+	b = append(b, '=')
+	if quoted {
+		b = append(b, '"')
+		b = append(b, value...)
+		return append(b, '"')
+	}
+	return append(b, value...)
+}
+
+// 检查类型是t的字段的引号，返回错误的原因，空表示正确
+func (f *attributeField) checkQuoted(t reflect.Type, a *Attribute) string {
+	quoted := f.quoted || t == timeType
+	// "NONE"也可以是名称
+	if f.none {
+		quoted = a.Quoted || a.Value != "NONE"
+	}
+	if a.Quoted == quoted {
+		return ""
+	}
+	if quoted {
+		return "must be quoted"
+	}
+	return "must not be quoted"
+}
+
+// 检查s是否是enums其中一个
+func (f *attributeField) checkEnum(s string) error {
+	if len(f.enums) < 1 {
+		return nil
+	}
+	for _, e := range f.enums {
+		if s == e {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid enumerated-string '%s', must be %s", s, strings.Join(f.enums, "|"))
+}
+
+// 把s转换成fv的类型并保存
+func (f *attributeField) setValue(fv reflect.Value, s string) error {
+	// 可选的值，比如*float64，*EXT_X_BYTERANGE
+	if fv.Kind() == reflect.Ptr {
+		p := reflect.New(fv.Type().Elem())
+		err := f.setValue(p.Elem(), s)
+		if err != nil {
+			return err
+		}
+		fv.Set(p)
+		return nil
+	}
+	if fv.Type() == timeType {
+		t, err := ParseDateTime(s)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := ParseDecimalInteger(s)
+		if err != nil {
+			return err
+		}
+		if fv.OverflowInt(n) {
+			return fmt.Errorf("decimal-integer '%s' overflow", s)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid decimal-integer '%s'", s)
+		}
+		if fv.OverflowUint(n) {
+			return fmt.Errorf("decimal-integer '%s' overflow", s)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		parse := ParseDecimalFloat
+		if f.signed {
+			parse = ParseSignedDecimalFloat
+		}
+		n, err := parse(s)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Bool:
+		b, err := ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		b, err := ParseHex(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBytes(b)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// 格式化fv，返回值和是否需要引号
+func (f *attributeField) formatValue(fv reflect.Value) (string, bool, error) {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return "", false, fmt.Errorf("missing value")
+		}
+		if !fv.Type().Implements(textMarshalerType) {
+			return f.formatValue(fv.Elem())
+		}
+	}
+	if fv.Type() == timeType {
+		return FormatDateTime(fv.Interface().(time.Time)), true, nil
+	}
+	if fv.Type().Implements(textMarshalerType) {
+		b, err := fv.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), f.quoted, err
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		s := fv.String()
+		if s == "" {
+			return "", false, fmt.Errorf("missing value")
+		}
+		if f.none {
+			return s, s != "NONE", nil
+		}
+		return s, f.quoted, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), false, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'f', f.prec, 64), false, nil
+	case reflect.Bool:
+		return FormatBool(fv.Bool()), false, nil
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			return FormatHex(fv.Bytes()), false, nil
+		}
+	}
+	return "", false, fmt.Errorf("unsupported type %s", fv.Type())
+}
@@ -0,0 +1,147 @@
+package m3u8
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// 使用所有选项的struct
+type testAttributes struct {
+	NAME    string           `m3u8:"NAME,quoted,required"`
+	TYPE    string           `m3u8:"TYPE,enum=A|B"`
+	CC      string           `m3u8:"CC,none"`
+	RATE    float64          `m3u8:"RATE,prec=3"`
+	OFFSET  float64          `m3u8:"OFFSET,signed"`
+	COUNT   int64            `m3u8:"COUNT"`
+	OPT     *float64         `m3u8:"OPT"`
+	FLAG    bool             `m3u8:"FLAG"`
+	DATA    []byte           `m3u8:"DATA"`
+	X       ClientAttributes `m3u8:"X-,prefix"`
+	Ignored string
+}
+
+func TestUnmarshalAttributes(t *testing.T) {
+	src := `NAME="n",TYPE=B,CC=NONE,RATE=29.97,OFFSET=-1.5,COUNT=3,OPT=0,FLAG=YES,DATA=0x0a0b,` +
+		`X-A="s",UNKNOWN=1,X-B=0x01,X-C=1.5`
+	var v testAttributes
+	if err := UnmarshalAttributes([]byte(src), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.NAME != "n" || v.TYPE != "B" || v.CC != "NONE" || v.RATE != 29.97 || v.OFFSET != -1.5 ||
+		v.COUNT != 3 || v.OPT == nil || *v.OPT != 0 || !v.FLAG || !bytes.Equal(v.DATA, []byte{0x0a, 0x0b}) {
+		t.Fatalf("got %+v", v)
+	}
+	// prefix按顺序保存，值的类型根据格式判断
+	want := ClientAttributes{{"X-A", ClientString, "s"}, {"X-B", ClientHex, "0x01"}, {"X-C", ClientFloat, "1.5"}}
+	if len(v.X) != len(want) {
+		t.Fatalf("got %v, want %v", v.X, want)
+	}
+	for i := range want {
+		if v.X[i] != want[i] {
+			t.Fatalf("got %v, want %v", v.X, want)
+		}
+	}
+	// prec=3，*float64的0也输出，没有的属性不输出
+	b, err := MarshalAttributes(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := `NAME="n",TYPE=B,CC=NONE,RATE=29.970,OFFSET=-1.5,COUNT=3,OPT=0,FLAG=YES,DATA=0x0a0b,X-A="s",X-B=0x01,X-C=1.5`
+	if string(b) != s {
+		t.Fatalf("got %s, want %s", b, s)
+	}
+	// none选项，不是NONE的值需要引号
+	v = testAttributes{}
+	if err := UnmarshalAttributes([]byte(`NAME="n",CC="cc1"`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.CC != "cc1" || v.OPT != nil {
+		t.Fatalf("got %+v", v)
+	}
+	b, err = MarshalAttributes(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := `NAME="n",CC="cc1"`; string(b) != s {
+		t.Fatalf("got %s, want %s", b, s)
+	}
+}
+
+func TestUnmarshalAttributesError(t *testing.T) {
+	for _, src := range []string{
+		// required
+		`TYPE=A`,
+		// 引号
+		`NAME=n`,
+		`NAME="n",TYPE="A"`,
+		`NAME="n",CC=cc1`,
+		// enum
+		`NAME="n",TYPE=C`,
+		// 没有signed不能是负数
+		`NAME="n",RATE=-1`,
+		`NAME="n",OFFSET=x`,
+		`NAME="n",COUNT=1.5`,
+		`NAME="n",FLAG=TRUE`,
+		`NAME="n",DATA=0xzz`,
+		`NAME="n",X-A=zz`,
+		// 重复的属性
+		`NAME="n",NAME="m"`,
+	} {
+		var v testAttributes
+		err := UnmarshalAttributes([]byte(src), &v)
+		if !errors.Is(err, ErrMalformedAttribute) {
+			t.Fatalf("%s: got %v, want %v", src, err, ErrMalformedAttribute)
+		}
+	}
+	var v testAttributes
+	if err := UnmarshalAttributes([]byte(`NAME="n"`), v); err == nil {
+		t.Fatal("non-pointer: want error")
+	}
+}
+
+func TestMarshalAttributesError(t *testing.T) {
+	for _, v := range []testAttributes{
+		// required的零值
+		{},
+		{NAME: "n", TYPE: "C"},
+		{NAME: "n", X: ClientAttributes{{"Y-A", ClientString, "s"}}},
+		{NAME: "n", X: ClientAttributes{{"X-A", ClientFloat, "s"}}},
+	} {
+		if b, err := MarshalAttributes(v); err == nil {
+			t.Fatalf("%+v: got %s, want error", v, b)
+		}
+	}
+}
+
+// 不支持的类型和错误的struct tag
+func TestAttributeFieldsError(t *testing.T) {
+	type unsupported struct {
+		M map[string]string `m3u8:"M"`
+	}
+	var u unsupported
+	if err := UnmarshalAttributes([]byte(`M=1`), &u); !errors.Is(err, ErrMalformedAttribute) {
+		t.Fatalf("got %v, want %v", err, ErrMalformedAttribute)
+	}
+	if _, err := MarshalAttributes(unsupported{M: map[string]string{"a": "b"}}); err == nil {
+		t.Fatal("want error")
+	}
+	for _, v := range []interface{}{
+		&struct {
+			A string `m3u8:",quoted"`
+		}{},
+		&struct {
+			A string `m3u8:"A,foo"`
+		}{},
+		&struct {
+			A float64 `m3u8:"A,prec=x"`
+		}{},
+		&struct {
+			A string `m3u8:"A,prefix"`
+		}{},
+	} {
+		if err := UnmarshalAttributes([]byte(`A=1`), v); err == nil {
+			t.Fatalf("%T: want error", v)
+		}
+	}
+}
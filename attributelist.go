@@ -0,0 +1,80 @@
+package m3u8
+
+// <attribute-list>中的一个属性
+type Attribute struct {
+	Name   string
+	Value  string // 没有引号的值
+	Quoted bool   // 是否<quoted-string>
+	column int    // 解析时在<attribute-list>中的列号
+}
+
+// 有顺序的<attribute-list>，可以修改某一个属性，
+// 然后按原来的顺序输出，不影响其他的属性。
+type AttributeList []Attribute
+
+// 返回name的索引，没有返回-1
+func (l AttributeList) Index(name string) int {
+	for i := 0; i < len(l); i++ {
+		if l[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// 返回name的值
+func (l AttributeList) Get(name string) (string, bool) {
+	i := l.Index(name)
+	if i < 0 {
+		return "", false
+	}
+	return l[i].Value, true
+}
+
+// 设置name的值，已经存在的在原来的位置修改，否则添加到最后
+func (l *AttributeList) Set(name, value string, quoted bool) {
+	i := l.Index(name)
+	if i < 0 {
+		*l = append(*l, Attribute{Name: name, Value: value, Quoted: quoted})
+		return
+	}
+	(*l)[i].Value = value
+	(*l)[i].Quoted = quoted
+}
+
+// 删除name
+func (l *AttributeList) Delete(name string) {
+	i := l.Index(name)
+	if i < 0 {
+		return
+	}
+	*l = append((*l)[:i], (*l)[i+1:]...)
+}
+
+// 格式化成<attribute-list>添加到b
+func (l AttributeList) Append(b []byte) []byte {
+	for i := 0; i < len(l); i++ {
+		b = appendAttribute(b, i == 0, l[i].Name, l[i].Value, l[i].Quoted)
+	}
+	return b
+}
+
+// 格式化成<attribute-list>
+func (l AttributeList) String() string {
+	return string(l.Append(nil))
+}
+
+// 实现encoding.TextMarshaler
+func (l AttributeList) MarshalText() ([]byte, error) {
+	return l.Append(nil), nil
+}
+
+// 实现encoding.TextUnmarshaler
+func (l *AttributeList) UnmarshalText(b []byte) error {
+	p, err := ParseAttributeList(b)
+	if err != nil {
+		return err
+	}
+	*l = p
+	return nil
+}
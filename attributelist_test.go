@@ -0,0 +1,108 @@
+package m3u8
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseAttributeList(t *testing.T) {
+	for _, c := range []struct {
+		line string
+		want AttributeList
+	}{
+		{`A=1`, AttributeList{{Name: "A", Value: "1"}}},
+		{`B=2,A="x,y",C=0x1F`, AttributeList{{Name: "B", Value: "2"}, {Name: "A", Value: "x,y", Quoted: true}, {Name: "C", Value: "0x1F"}}},
+		{`A="",B=-1.5`, AttributeList{{Name: "A", Quoted: true}, {Name: "B", Value: "-1.5"}}},
+		// 没有转义字符，'\'是普通的字符
+		{`URI="C:\",X=1`, AttributeList{{Name: "URI", Value: `C:\`, Quoted: true}, {Name: "X", Value: "1"}}},
+	} {
+		l, err := ParseAttributeList([]byte(c.line))
+		if err != nil {
+			t.Fatalf("%s: %v", c.line, err)
+		}
+		if len(l) != len(c.want) {
+			t.Fatalf("%s: got %v, want %v", c.line, l, c.want)
+		}
+		for i := range l {
+			a, b := l[i], c.want[i]
+			if a.Name != b.Name || a.Value != b.Value || a.Quoted != b.Quoted {
+				t.Fatalf("%s: attribute %d got %+v, want %+v", c.line, i, a, b)
+			}
+		}
+		// 按原来的顺序输出
+		if s := l.String(); s != c.line {
+			t.Fatalf("got %s, want %s", s, c.line)
+		}
+	}
+}
+
+func TestParseAttributeListError(t *testing.T) {
+	for _, c := range []struct {
+		line   string
+		column int
+	}{
+		{`A`, 1},
+		{`=1`, 1},
+		{`A=`, 1},
+		{`A=1,B="x`, 5},
+		{`A="x"B=1`, 6},
+		{`A=1,a=2`, 5},
+		{`A=1,B=2,A=3`, 9},
+		{`A=1,`, 4},
+	} {
+		_, err := ParseAttributeList([]byte(c.line))
+		if !errors.Is(err, ErrMalformedAttribute) {
+			t.Fatalf("%s: got %v, want ErrMalformedAttribute", c.line, err)
+		}
+		var e *ParseError
+		if !errors.As(err, &e) || e.Column != c.column {
+			t.Fatalf("%s: got %v, want column %d", c.line, err, c.column)
+		}
+	}
+}
+
+func TestAttributeListSet(t *testing.T) {
+	l, err := ParseAttributeList([]byte(`TYPE=AUDIO,GROUP-ID="aac",NAME="English",X-CUSTOM=1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Set("NAME", "Deutsch", true)
+	l.Set("DEFAULT", "YES", false)
+	l.Delete("X-CUSTOM")
+	l.Delete("NOT-EXIST")
+	want := `TYPE=AUDIO,GROUP-ID="aac",NAME="Deutsch",DEFAULT=YES`
+	if s := l.String(); s != want {
+		t.Fatalf("got %s, want %s", s, want)
+	}
+	if v, ok := l.Get("GROUP-ID"); !ok || v != "aac" {
+		t.Fatalf("got %q %v", v, ok)
+	}
+	if _, ok := l.Get("X-CUSTOM"); ok {
+		t.Fatal("X-CUSTOM is not deleted")
+	}
+	var l2 AttributeList
+	if err := l2.UnmarshalText([]byte(want)); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := l2.MarshalText(); string(b) != want {
+		t.Fatalf("got %s, want %s", b, want)
+	}
+}
+
+// 宽松模式修复格式错误
+func TestParseAttributeListLenient(t *testing.T) {
+	var columns []int
+	warn := func(column int, format string, args ...interface{}) {
+		columns = append(columns, column)
+	}
+	l, err := parseAttributeList([]byte(`A=1, B=2,A=3,`), warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := l.String(); s != `A=1,B=2` {
+		t.Fatalf("got %s", s)
+	}
+	if len(columns) != 3 || columns[0] != 5 || columns[1] != 10 || columns[2] != 13 {
+		t.Fatalf("got warnings at %v", columns)
+	}
+}
@@ -0,0 +1,136 @@
+package m3u8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 客户端属性的值的类型，RFC8216 4.3.2.7.1
+type ClientValueType int
+
+const (
+	// <quoted-string>
+	ClientString ClientValueType = iota
+	// <hexadecimal-sequence>
+	ClientHex
+	// <decimal-floating-point>
+	ClientFloat
+)
+
+func (t ClientValueType) String() string {
+	switch t {
+	case ClientString:
+		return "quoted-string"
+	case ClientHex:
+		return "hexadecimal-sequence"
+	case ClientFloat:
+		return "decimal-floating-point"
+	default:
+		return "unknown"
+	}
+}
+
+// #EXT-X-DATERANGE中X-开头的一个属性
+type ClientAttribute struct {
+	Name  string // 包括X-
+	Type  ClientValueType
+	Value string // 没有引号的值，ClientHex包括0x
+}
+
+// 有顺序的客户端属性，解析和输出都保持原来的顺序
+type ClientAttributes []ClientAttribute
+
+// 返回name的索引，没有返回-1
+func (l ClientAttributes) Index(name string) int {
+	for i := 0; i < len(l); i++ {
+		if l[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// 返回name的值
+func (l ClientAttributes) Get(name string) (string, bool) {
+	i := l.Index(name)
+	if i < 0 {
+		return "", false
+	}
+	return l[i].Value, true
+}
+
+// 设置name的值，已经存在的在原来的位置修改，否则添加到最后
+func (l *ClientAttributes) Set(name string, t ClientValueType, value string) {
+	i := l.Index(name)
+	if i < 0 {
+		*l = append(*l, ClientAttribute{Name: name, Type: t, Value: value})
+		return
+	}
+	(*l)[i].Type = t
+	(*l)[i].Value = value
+}
+
+// 设置<quoted-string>
+func (l *ClientAttributes) SetString(name, value string) {
+	l.Set(name, ClientString, value)
+}
+
+// 设置<hexadecimal-sequence>
+func (l *ClientAttributes) SetHex(name string, value []byte) {
+	l.Set(name, ClientHex, FormatHex(value))
+}
+
+// 设置<decimal-floating-point>
+func (l *ClientAttributes) SetFloat(name string, value float64) {
+	l.Set(name, ClientFloat, FormatDecimalFloat(value))
+}
+
+// 删除name
+func (l *ClientAttributes) Delete(name string) {
+	i := l.Index(name)
+	if i < 0 {
+		return
+	}
+	*l = append((*l)[:i], (*l)[i+1:]...)
+}
+
+// 检查名称是否以prefix开头，值是否符合类型
+func (a *ClientAttribute) check(prefix string) error {
+	if !strings.HasPrefix(a.Name, prefix) || len(a.Name) <= len(prefix) {
+		return fmt.Errorf("invalid attribute name '%s', must start with '%s'", a.Name, prefix)
+	}
+	if !isAttributeName(a.Name) {
+		return fmt.Errorf("invalid attribute name '%s'", a.Name)
+	}
+	var err error
+	switch a.Type {
+	case ClientString:
+		if strings.ContainsAny(a.Value, "\"\r\n") {
+			err = fmt.Errorf("invalid quoted-string '%s'", a.Value)
+		}
+	case ClientHex:
+		_, err = ParseHex(a.Value)
+	case ClientFloat:
+		_, err = ParseDecimalFloat(a.Value)
+	default:
+		err = fmt.Errorf("unknown type %d", a.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("%s %v", a.Name, err)
+	}
+	return nil
+}
+
+// 解析出来的属性的类型，有引号的是ClientString，0x开头的是ClientHex，其他的是ClientFloat
+func parseClientAttribute(a *Attribute) (ClientAttribute, error) {
+	c := ClientAttribute{Name: a.Name, Value: a.Value}
+	switch {
+	case a.Quoted:
+		c.Type = ClientString
+	case hasHexPrefix(a.Value):
+		c.Type = ClientHex
+	default:
+		c.Type = ClientFloat
+	}
+	return c, c.check("")
+}
@@ -0,0 +1,95 @@
+package m3u8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// 修改在原来的位置，添加在最后，输出保持顺序
+func TestClientAttributesOrder(t *testing.T) {
+	r := EXT_X_DATERANGE{ID: "a", START_DATE: testStart}
+	a := &r.X_CLIENT_ATTRIBUTE
+	a.SetString("X-A", "a")
+	a.SetHex("X-B", []byte{1})
+	a.SetFloat("X-C", 1.5)
+	a.SetFloat("X-A", 2)
+	a.Delete("X-B")
+	a.Delete("X-D")
+	a.SetString("X-B", "b")
+	want := ClientAttributes{{"X-A", ClientFloat, "2"}, {"X-C", ClientFloat, "1.5"}, {"X-B", ClientString, "b"}}
+	if len(*a) != len(want) {
+		t.Fatalf("got %v, want %v", *a, want)
+	}
+	for i := range want {
+		if (*a)[i] != want[i] {
+			t.Fatalf("got %v, want %v", *a, want)
+		}
+	}
+	if v, ok := a.Get("X-C"); !ok || v != "1.5" {
+		t.Fatalf("got %s %v", v, ok)
+	}
+	if _, ok := a.Get("X-D"); ok {
+		t.Fatal("X-D: want not found")
+	}
+	var b bytes.Buffer
+	if _, err := NewWriter(&b).EXT_X_DATERANGE(&r); err != nil {
+		t.Fatal(err)
+	}
+	if s := "#EXT-X-DATERANGE:ID=\"a\",START-DATE=\"2020-01-01T00:00:00.000Z\",X-A=2,X-C=1.5,X-B=\"b\"\n"; b.String() != s {
+		t.Fatalf("got %s, want %s", b.String(), s)
+	}
+}
+
+// 输出时检查X-前缀和值的格式
+func TestClientAttributesEncodeError(t *testing.T) {
+	for _, a := range []ClientAttribute{
+		{"A", ClientString, "a"},
+		{"X-", ClientString, "a"},
+		{"X-a", ClientString, "a"},
+		{"X-A", ClientString, "a\"b"},
+		{"X-A", ClientString, "a\nb"},
+		{"X-A", ClientHex, "12"},
+		{"X-A", ClientHex, "0xzz"},
+		{"X-A", ClientFloat, "a"},
+		{"X-A", ClientFloat, "-1"},
+		{"X-A", ClientValueType(9), "1"},
+	} {
+		r := EXT_X_DATERANGE{ID: "a", START_DATE: testStart, X_CLIENT_ATTRIBUTE: ClientAttributes{a}}
+		var b bytes.Buffer
+		if _, err := NewWriter(&b).EXT_X_DATERANGE(&r); err == nil {
+			t.Fatalf("%v: got %s, want error", a, b.String())
+		}
+	}
+}
+
+// 解析时根据格式判断类型
+func TestClientAttributesDecode(t *testing.T) {
+	media := func(attrs string) string {
+		return "#EXTM3U\n" +
+			"#EXT-X-TARGETDURATION:10\n" +
+			"#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00.000Z\n" +
+			"#EXT-X-DATERANGE:ID=\"a\",START-DATE=\"2020-01-01T00:00:00.000Z\"," + attrs + "\n" +
+			"#EXTINF:10,\n" +
+			"a.ts\n"
+	}
+	p, err := ParseMediaPlayList(strings.NewReader(media(`X-B="0x01",X-A=0x0A,X-C=1.5`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ClientAttributes{{"X-B", ClientString, "0x01"}, {"X-A", ClientHex, "0x0A"}, {"X-C", ClientFloat, "1.5"}}
+	a := p.MediaSegment[0].EXT_X_DATERANGE[0].X_CLIENT_ATTRIBUTE
+	if len(a) != len(want) {
+		t.Fatalf("got %v, want %v", a, want)
+	}
+	for i := range want {
+		if a[i] != want[i] {
+			t.Fatalf("got %v, want %v", a, want)
+		}
+	}
+	for _, attrs := range []string{`X-A=zz`, `X-A=0xzz`, `X-A=-1`, `X-A=1,X-A=2`} {
+		if _, err := ParseMediaPlayList(strings.NewReader(media(attrs))); err == nil {
+			t.Fatalf("%s: want error", attrs)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package m3u8
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// DateRangeSet中已经有这个ID
+	ErrDateRangeExists = errors.New("date range already exists")
+	// DateRangeSet中没有这个ID
+	ErrDateRangeNotFound = errors.New("date range not found")
+)
+
+// 直播时管理#EXT-X-DATERANGE，Open，Update，Close都会检查RFC8216 4.3.2.7的规则，
+// 每次刷新列表时使用Apply输出，可以并发使用
+type DateRangeSet struct {
+	lock   sync.Mutex
+	ranges []*dateRange // 按照Open的顺序
+	index  map[string]*dateRange
+}
+
+type dateRange struct {
+	tag EXT_X_DATERANGE
+	end time.Time // 结束的时间，零值表示没有结束
+}
+
+// 开始一个新的date range，ID不能重复，END-ON-NEXT会结束前面CLASS一样的END-ON-NEXT
+func (s *DateRangeSet) Open(r *EXT_X_DATERANGE) error {
+	err := checkEXT_X_DATERANGE(r)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.index == nil {
+		s.index = make(map[string]*dateRange)
+	}
+	if _, ok := s.index[r.ID]; ok {
+		return fmt.Errorf("%w '%s'", ErrDateRangeExists, r.ID)
+	}
+	if r.END_ON_NEXT {
+		for _, d := range s.ranges {
+			if d.tag.END_ON_NEXT && d.end.IsZero() && d.tag.CLASS == r.CLASS {
+				d.end = r.START_DATE
+			}
+		}
+	}
+	d := &dateRange{tag: copyEXT_X_DATERANGE(r)}
+	d.end = d.tag.endDate()
+	s.ranges = append(s.ranges, d)
+	s.index[r.ID] = d
+	return nil
+}
+
+// 添加r中的属性到ID一样的date range，已经有的属性不能修改，RFC8216 4.3.2.7
+func (s *DateRangeSet) Update(r *EXT_X_DATERANGE) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	d, ok := s.index[r.ID]
+	if !ok {
+		return fmt.Errorf("%w '%s'", ErrDateRangeNotFound, r.ID)
+	}
+	t := copyEXT_X_DATERANGE(&d.tag)
+	err := mergeEXT_X_DATERANGE(&t, r)
+	if err != nil {
+		return fmt.Errorf("ID '%s' %v", r.ID, err)
+	}
+	err = checkEXT_X_DATERANGE(&t)
+	if err != nil {
+		return err
+	}
+	d.tag = t
+	if e := t.endDate(); !e.IsZero() {
+		d.end = e
+	}
+	return nil
+}
+
+// 结束ID是id的date range，设置END-DATE，已经有DURATION或者END-DATE时必须一样，
+// END-ON-NEXT的不能使用Close
+func (s *DateRangeSet) Close(id string, end time.Time) error {
+	return s.Update(&EXT_X_DATERANGE{ID: id, END_DATE: end})
+}
+
+// 返回ID是id的date range的副本，没有返回nil
+func (s *DateRangeSet) Get(id string) *EXT_X_DATERANGE {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	d, ok := s.index[id]
+	if !ok {
+		return nil
+	}
+	t := copyEXT_X_DATERANGE(&d.tag)
+	return &t
+}
+
+// 返回在from的时候还没有结束的date range，按照Open的顺序
+func (s *DateRangeSet) DateRanges(from time.Time) []EXT_X_DATERANGE {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var r []EXT_X_DATERANGE
+	for _, d := range s.ranges {
+		if d.end.IsZero() || !d.end.Before(from) {
+			r = append(r, copyEXT_X_DATERANGE(&d.tag))
+		}
+	}
+	return r
+}
+
+// 删除在before之前已经结束的date range，返回删除的ID，
+// 可以用在#EXT-X-SKIP的RECENTLY-REMOVED-DATERANGES
+func (s *DateRangeSet) Expire(before time.Time) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var ids []string
+	ranges := s.ranges[:0]
+	for _, d := range s.ranges {
+		if !d.end.IsZero() && d.end.Before(before) {
+			ids = append(ids, d.tag.ID)
+			delete(s.index, d.tag.ID)
+			continue
+		}
+		ranges = append(ranges, d)
+	}
+	for i := len(ranges); i < len(s.ranges); i++ {
+		s.ranges[i] = nil
+	}
+	s.ranges = ranges
+	return ids
+}
+
+// 把DateRanges的结果放到p的第一个片段，先删除片段中ID在s中的#EXT-X-DATERANGE。
+// from是第一个片段的开始时间，根据#EXT-X-PROGRAM-DATE-TIME计算
+func (s *DateRangeSet) Apply(p *MediaPlayList) error {
+	if len(p.MediaSegment) < 1 {
+		return nil
+	}
+	from, ok := p.startDate()
+	if !ok {
+		return fmt.Errorf("%s requires %s", TagEXT_X_DATERANGE, TagEXT_X_PROGRAM_DATE_TIME)
+	}
+	s.lock.Lock()
+	for i := 0; i < len(p.MediaSegment); i++ {
+		seg := &p.MediaSegment[i]
+		ranges := seg.EXT_X_DATERANGE[:0]
+		for _, r := range seg.EXT_X_DATERANGE {
+			if _, ok := s.index[r.ID]; !ok {
+				ranges = append(ranges, r)
+			}
+		}
+		seg.EXT_X_DATERANGE = ranges
+	}
+	s.lock.Unlock()
+	seg := &p.MediaSegment[0]
+	seg.EXT_X_DATERANGE = append(s.DateRanges(from), seg.EXT_X_DATERANGE...)
+	return nil
+}
+
+// 第一个片段的开始时间，第一个#EXT-X-PROGRAM-DATE-TIME减去前面片段的时长
+func (p *MediaPlayList) startDate() (time.Time, bool) {
+	var d float64
+	for i := 0; i < len(p.MediaSegment); i++ {
+		s := &p.MediaSegment[i]
+		if !s.EXT_X_PROGRAM_DATE_TIME.IsZero() {
+			return s.EXT_X_PROGRAM_DATE_TIME.Add(-time.Duration(d * float64(time.Second))), true
+		}
+		d += s.EXTINF.DURATION
+	}
+	return time.Time{}, false
+}
+
+// 结束的时间，END-DATE或者START-DATE加上DURATION，没有返回零值
+func (r *EXT_X_DATERANGE) endDate() time.Time {
+	if !r.END_DATE.IsZero() {
+		return r.END_DATE
+	}
+	if r.DURATION != nil {
+		return r.START_DATE.Add(time.Duration(*r.DURATION * float64(time.Second)))
+	}
+	return time.Time{}
+}
+
+// 检查RFC8216 4.3.2.7的规则，返回所有的错误，DateRangeSet和validator包都使用它
+func (r *EXT_X_DATERANGE) Check() []error {
+	var errs []error
+	if r.ID == "" {
+		errs = append(errs, errors.New("missing ID"))
+	}
+	if r.START_DATE.IsZero() {
+		errs = append(errs, errors.New("missing START-DATE"))
+	}
+	if !r.END_DATE.IsZero() && r.END_DATE.Before(r.START_DATE) {
+		errs = append(errs, errors.New("END-DATE is earlier than START-DATE"))
+	}
+	if r.DURATION != nil {
+		if *r.DURATION < 0 {
+			errs = append(errs, errors.New("DURATION must not be negative"))
+		}
+		if !r.END_DATE.IsZero() {
+			end := r.START_DATE.Add(time.Duration(*r.DURATION * float64(time.Second)))
+			// 精度是毫秒
+			if d := end.Sub(r.END_DATE); d > time.Millisecond || d < -time.Millisecond {
+				errs = append(errs, errors.New("END-DATE is not equal to START-DATE plus DURATION"))
+			}
+		}
+	}
+	if r.PLANNED_DURATION != nil && *r.PLANNED_DURATION < 0 {
+		errs = append(errs, errors.New("PLANNED-DURATION must not be negative"))
+	}
+	if r.END_ON_NEXT {
+		if r.CLASS == "" {
+			errs = append(errs, errors.New("END-ON-NEXT requires CLASS"))
+		}
+		if r.DURATION != nil || !r.END_DATE.IsZero() {
+			errs = append(errs, errors.New("END-ON-NEXT must not be used with DURATION or END-DATE"))
+		}
+	}
+	return errs
+}
+
+// 返回Check的第一个错误，有ID的加上ID
+func checkEXT_X_DATERANGE(r *EXT_X_DATERANGE) error {
+	errs := r.Check()
+	if len(errs) < 1 {
+		return nil
+	}
+	if r.ID == "" {
+		return errs[0]
+	}
+	return fmt.Errorf("ID '%s' %w", r.ID, errs[0])
+}
+
+// 把r2的属性合并到r1，r1已经有的属性不能修改
+func mergeEXT_X_DATERANGE(r1, r2 *EXT_X_DATERANGE) error {
+	mergeString := func(name string, v1 *string, v2 string) error {
+		if v2 == "" {
+			return nil
+		}
+		if *v1 != "" && *v1 != v2 {
+			return fmt.Errorf("can not change %s", name)
+		}
+		*v1 = v2
+		return nil
+	}
+	mergeTime := func(name string, v1 *time.Time, v2 time.Time) error {
+		if v2.IsZero() {
+			return nil
+		}
+		if !v1.IsZero() && !v1.Equal(v2) {
+			return fmt.Errorf("can not change %s", name)
+		}
+		*v1 = v2
+		return nil
+	}
+	mergeFloat := func(name string, v1 **float64, v2 *float64) error {
+		if v2 == nil {
+			return nil
+		}
+		if *v1 != nil && **v1 != *v2 {
+			return fmt.Errorf("can not change %s", name)
+		}
+		f := *v2
+		*v1 = &f
+		return nil
+	}
+	mergeBytes := func(name string, v1 *[]byte, v2 []byte) error {
+		if len(v2) < 1 {
+			return nil
+		}
+		if len(*v1) > 0 && !bytes.Equal(*v1, v2) {
+			return fmt.Errorf("can not change %s", name)
+		}
+		*v1 = append([]byte(nil), v2...)
+		return nil
+	}
+	for _, err := range []error{
+		mergeString("CLASS", &r1.CLASS, r2.CLASS),
+		mergeTime("START-DATE", &r1.START_DATE, r2.START_DATE),
+		mergeString("CUE", &r1.CUE, r2.CUE),
+		mergeTime("END-DATE", &r1.END_DATE, r2.END_DATE),
+		mergeFloat("DURATION", &r1.DURATION, r2.DURATION),
+		mergeFloat("PLANNED-DURATION", &r1.PLANNED_DURATION, r2.PLANNED_DURATION),
+		mergeBytes("SCTE35-CMD", &r1.SCTE35_CMD, r2.SCTE35_CMD),
+		mergeBytes("SCTE35-OUT", &r1.SCTE35_OUT, r2.SCTE35_OUT),
+		mergeBytes("SCTE35-IN", &r1.SCTE35_IN, r2.SCTE35_IN),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	for _, a := range r2.X_CLIENT_ATTRIBUTE {
+		i := r1.X_CLIENT_ATTRIBUTE.Index(a.Name)
+		if i >= 0 && r1.X_CLIENT_ATTRIBUTE[i] != a {
+			return fmt.Errorf("can not change %s", a.Name)
+		}
+		r1.X_CLIENT_ATTRIBUTE.Set(a.Name, a.Type, a.Value)
+	}
+	if r2.END_ON_NEXT {
+		r1.END_ON_NEXT = true
+	}
+	return nil
+}
+
+// 复制r，不共享slice
+func copyEXT_X_DATERANGE(r *EXT_X_DATERANGE) EXT_X_DATERANGE {
+	c := *r
+	if r.DURATION != nil {
+		f := *r.DURATION
+		c.DURATION = &f
+	}
+	if r.PLANNED_DURATION != nil {
+		f := *r.PLANNED_DURATION
+		c.PLANNED_DURATION = &f
+	}
+	c.X_CLIENT_ATTRIBUTE = append(ClientAttributes(nil), r.X_CLIENT_ATTRIBUTE...)
+	c.SCTE35_CMD = append([]byte(nil), r.SCTE35_CMD...)
+	c.SCTE35_OUT = append([]byte(nil), r.SCTE35_OUT...)
+	c.SCTE35_IN = append([]byte(nil), r.SCTE35_IN...)
+	return c
+}
@@ -0,0 +1,175 @@
+package m3u8
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestDateRangeSetRules(t *testing.T) {
+	var s DateRangeSet
+	for _, r := range []EXT_X_DATERANGE{
+		{START_DATE: testStart},
+		{ID: "a"},
+		{ID: "a", START_DATE: testStart, END_DATE: testStart.Add(-time.Second)},
+		{ID: "a", START_DATE: testStart, DURATION: float(-1)},
+		{ID: "a", START_DATE: testStart, DURATION: float(10), END_DATE: testStart.Add(20 * time.Second)},
+		{ID: "a", START_DATE: testStart, PLANNED_DURATION: float(-1)},
+		{ID: "a", START_DATE: testStart, END_ON_NEXT: true},
+		{ID: "a", CLASS: "c", START_DATE: testStart, END_ON_NEXT: true, DURATION: float(10)},
+	} {
+		r := r
+		if err := s.Open(&r); err == nil {
+			t.Fatalf("%+v: want error", r)
+		}
+	}
+	r := EXT_X_DATERANGE{ID: "a", START_DATE: testStart, PLANNED_DURATION: float(30), DURATION: float(10), END_DATE: testStart.Add(10 * time.Second)}
+	if err := s.Open(&r); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Open(&r); !errors.Is(err, ErrDateRangeExists) {
+		t.Fatalf("got %v, want ErrDateRangeExists", err)
+	}
+	if err := s.Update(&EXT_X_DATERANGE{ID: "b"}); !errors.Is(err, ErrDateRangeNotFound) {
+		t.Fatalf("got %v, want ErrDateRangeNotFound", err)
+	}
+}
+
+// Check返回所有的错误，DateRangeSet返回第一个，加上ID
+func TestDateRangeCheck(t *testing.T) {
+	r := EXT_X_DATERANGE{ID: "a", END_DATE: testStart, PLANNED_DURATION: float(-1), END_ON_NEXT: true}
+	want := []string{
+		"missing START-DATE",
+		"PLANNED-DURATION must not be negative",
+		"END-ON-NEXT requires CLASS",
+		"END-ON-NEXT must not be used with DURATION or END-DATE",
+	}
+	errs := r.Check()
+	if len(errs) != len(want) {
+		t.Fatalf("got %v, want %v", errs, want)
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Fatalf("got %v, want %v", errs, want)
+		}
+	}
+	var s DateRangeSet
+	if err := s.Open(&r); err == nil || err.Error() != "ID 'a' missing START-DATE" {
+		t.Fatalf("got %v", err)
+	}
+	r = EXT_X_DATERANGE{ID: "a", START_DATE: testStart}
+	if errs := r.Check(); len(errs) != 0 {
+		t.Fatalf("got %v", errs)
+	}
+}
+
+// 已经有的属性不能修改，可以添加新的属性
+func TestDateRangeSetUpdate(t *testing.T) {
+	var s DateRangeSet
+	r := EXT_X_DATERANGE{ID: "a", CLASS: "c", START_DATE: testStart, PLANNED_DURATION: float(30)}
+	r.X_CLIENT_ATTRIBUTE.SetString("X-COM-EXAMPLE-AD-ID", "1")
+	if err := s.Open(&r); err != nil {
+		t.Fatal(err)
+	}
+	// Open复制了r
+	r.CLASS = "d"
+	for _, u := range []EXT_X_DATERANGE{
+		{ID: "a", CLASS: "d"},
+		{ID: "a", START_DATE: testStart.Add(time.Second)},
+		{ID: "a", PLANNED_DURATION: float(20)},
+		{ID: "a", X_CLIENT_ATTRIBUTE: ClientAttributes{{Name: "X-COM-EXAMPLE-AD-ID", Type: ClientString, Value: "2"}}},
+	} {
+		u := u
+		if err := s.Update(&u); err == nil {
+			t.Fatalf("%+v: want error", u)
+		}
+	}
+	u := EXT_X_DATERANGE{ID: "a", CLASS: "c", SCTE35_OUT: []byte{0xFC}}
+	u.X_CLIENT_ATTRIBUTE.SetHex("X-COM-EXAMPLE-BEACON", []byte{1})
+	if err := s.Update(&u); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close("a", testStart.Add(-time.Second)); err == nil {
+		t.Fatal("want error")
+	}
+	if err := s.Close("a", testStart.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	g := s.Get("a")
+	if g == nil || g.CLASS != "c" || len(g.SCTE35_OUT) != 1 || len(g.X_CLIENT_ATTRIBUTE) != 2 ||
+		!g.END_DATE.Equal(testStart.Add(30*time.Second)) {
+		t.Fatalf("got %+v", g)
+	}
+	// Get返回的是副本
+	g.SCTE35_OUT[0] = 0
+	if s.Get("a").SCTE35_OUT[0] != 0xFC {
+		t.Fatal("Get() returns shared data")
+	}
+	if s.Get("b") != nil {
+		t.Fatal("Get(\"b\") got not nil")
+	}
+}
+
+func TestDateRangeSetEndOnNext(t *testing.T) {
+	var s DateRangeSet
+	for i, id := range []string{"a", "b"} {
+		r := EXT_X_DATERANGE{ID: id, CLASS: "c", START_DATE: testStart.Add(time.Duration(i) * time.Minute), END_ON_NEXT: true}
+		if err := s.Open(&r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// END-ON-NEXT的不能使用Close
+	if err := s.Close("b", testStart.Add(2*time.Minute)); err == nil {
+		t.Fatal("want error")
+	}
+	// a在b开始的时候结束
+	ids := s.Expire(testStart.Add(time.Minute + time.Second))
+	if len(ids) != 1 || ids[0] != "a" {
+		t.Fatalf("got %v", ids)
+	}
+	if r := s.DateRanges(testStart); len(r) != 1 || r[0].ID != "b" {
+		t.Fatalf("got %v", r)
+	}
+}
+
+func TestDateRangeSetApply(t *testing.T) {
+	var s DateRangeSet
+	for _, r := range []EXT_X_DATERANGE{
+		{ID: "a", START_DATE: testStart, DURATION: float(10)},
+		{ID: "b", START_DATE: testStart.Add(20 * time.Second)},
+	} {
+		r := r
+		if err := s.Open(&r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := &MediaPlayList{
+		EXT_X_TARGETDURATION: 10,
+		MediaSegment: []MediaSegment{
+			{EXTINF: EXTINF{DURATION: 10}, URI: "2.ts", EXT_X_DATERANGE: []EXT_X_DATERANGE{{ID: "b", START_DATE: testStart}}},
+			{EXTINF: EXTINF{DURATION: 10}, URI: "3.ts", EXT_X_PROGRAM_DATE_TIME: testStart.Add(30 * time.Second),
+				EXT_X_DATERANGE: []EXT_X_DATERANGE{{ID: "other", START_DATE: testStart}}},
+		},
+	}
+	// 第一个片段从20秒开始，a已经结束，b在s中，替换片段中的b
+	if err := s.Apply(p); err != nil {
+		t.Fatal(err)
+	}
+	if r := p.MediaSegment[0].EXT_X_DATERANGE; len(r) != 1 || r[0].ID != "b" || !r[0].START_DATE.Equal(testStart.Add(20*time.Second)) {
+		t.Fatalf("segment 0: %+v", r)
+	}
+	if r := p.MediaSegment[1].EXT_X_DATERANGE; len(r) != 1 || r[0].ID != "other" {
+		t.Fatalf("segment 1: %+v", r)
+	}
+	// 没有#EXT-X-PROGRAM-DATE-TIME
+	p.MediaSegment[1].EXT_X_PROGRAM_DATE_TIME = time.Time{}
+	if err := s.Apply(p); err == nil {
+		t.Fatal("want error")
+	}
+}
@@ -0,0 +1,1099 @@
+package m3u8
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 所有tag的前缀，不是这个前缀的是注释
+var tagEXT = []byte("#EXT")
+
+// RFC8216的tag的前缀
+var tagEXT_X = []byte("#EXT-X-")
+
+// 列表的类型
+type PlayListType int
+
+const (
+	MediaPlayListType PlayListType = iota + 1
+	MasterPlayListType
+)
+
+func (t PlayListType) String() string {
+	switch t {
+	case MediaPlayListType:
+		return "media playlist"
+	case MasterPlayListType:
+		return "master playlist"
+	default:
+		return "unknown playlist"
+	}
+}
+
+// MediaPlayList和MasterPlayList的公共接口
+type PlayList interface {
+	io.WriterTo
+	Type() PlayListType
+}
+
+func (p *MediaPlayList) Type() PlayListType {
+	return MediaPlayListType
+}
+
+func (p *MasterPlayList) Type() PlayListType {
+	return MasterPlayListType
+}
+
+// 解析的模式
+type ParseMode int
+
+const (
+	// 严格模式，遇到任何不符合RFC8216的地方都返回错误
+	Strict ParseMode = iota
+	// 宽松模式，尽量修复不符合RFC8216的地方，每个修复记录一个Warning
+	Lenient
+)
+
+// UTF-8的BOM
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// 解析列表，每个Decoder只能解析一个列表
+type Decoder struct {
+	reader         *Reader           // 数据源
+	mode           ParseMode         // 解析模式
+	warnings       []Warning         // 宽松模式下的修复记录
+	unknownTag     bool              // 不认识的#EXT-X-的tag是否返回ErrUnknownTag
+	tags           *TagRegistry      // 自定义tag
+	parent         *MasterPlayList   // #EXT-X-DEFINE的IMPORT使用的主列表
+	url            *url.URL          // #EXT-X-DEFINE的QUERYPARAM使用的列表的URL
+	variables      map[string]string // #EXT-X-DEFINE定义的变量
+	tag            []byte            // 正在解析的tag
+	kind           PlayListType      // 根据tag判断出来的列表类型，0表示还不知道
+	media          *MediaPlayList    // 媒体列表
+	segment        *MediaSegment     // 正在解析的片段，遇到URI时结束
+	segments       int               // 已经解析的片段的数量
+	index          int               // 当前行在所在部分中的行号，见UnknownLine
+	maxDuration    int64             // 四舍五入后最大的片段时长
+	handler        Handler           // Scanner的事件处理，不是nil时不保存片段
+	header         bool              // 是否已经调用了handler.OnHeader
+	extinf         bool              // 正在解析的片段是否有#EXTINF
+	targetDuration bool              // 是否有#EXT-X-TARGETDURATION
+	keys           []*EXT_X_KEY      // 当前生效的#EXT-X-KEY
+	xmap           *EXT_X_MAP        // 当前生效的#EXT-X-MAP
+	bitrate        int64             // 当前生效的#EXT-X-BITRATE
+	master         *MasterPlayList   // 主列表
+	streamInf      *EXT_X_STREAM_INF // 正在解析的#EXT-X-STREAM-INF，遇到URI时结束
+}
+
+// 从r读取并解析列表，根据tag判断是媒体列表还是主列表，
+// 返回的是*MediaPlayList或者*MasterPlayList，使用严格模式
+func Decode(r io.Reader) (PlayList, error) {
+	return NewDecoder(r, Strict).Decode()
+}
+
+// 从r读取并解析媒体列表，使用严格模式
+func ParseMediaPlayList(r io.Reader) (*MediaPlayList, error) {
+	return NewDecoder(r, Strict).DecodeMedia()
+}
+
+// 从r读取并解析主列表，使用严格模式
+func ParseMasterPlayList(r io.Reader) (*MasterPlayList, error) {
+	return NewDecoder(r, Strict).DecodeMaster()
+}
+
+// 创建Decoder，r是数据源，mode是解析模式
+func NewDecoder(r io.Reader, mode ParseMode) *Decoder {
+	d := new(Decoder)
+	d.reader = NewReader(r, nil)
+	d.mode = mode
+	d.tags = DefaultTagRegistry
+	d.media = new(MediaPlayList)
+	d.master = new(MasterPlayList)
+	return d
+}
+
+// 设置解析自定义tag的注册表，默认是DefaultTagRegistry
+func (d *Decoder) SetTagRegistry(r *TagRegistry) {
+	d.tags = r
+}
+
+// 设置不认识并且没有注册的#EXT-X-的tag是否返回ErrUnknownTag，默认不返回。
+// RFC8216 6.3.1要求客户端忽略不认识的tag，所以两种模式默认都保存到Unknown中
+func (d *Decoder) SetUnknownTagError(enable bool) {
+	d.unknownTag = enable
+}
+
+// 设置媒体列表所属的主列表，#EXT-X-DEFINE的IMPORT从它的Variables()中导入变量
+func (d *Decoder) SetParent(parent *MasterPlayList) {
+	d.parent = parent
+}
+
+// 设置列表的URL，#EXT-X-DEFINE的QUERYPARAM从它的query中获取变量的值
+func (d *Decoder) SetURL(u *url.URL) {
+	d.url = u
+}
+
+// 宽松模式下修复的记录，解析完成后调用
+func (d *Decoder) Warnings() []Warning {
+	return d.warnings
+}
+
+// 解析列表，根据tag判断是媒体列表还是主列表，
+// 返回的是*MediaPlayList或者*MasterPlayList
+func (d *Decoder) Decode() (PlayList, error) {
+	err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.kind == MasterPlayListType {
+		err = d.endMaster()
+		if err != nil {
+			return nil, err
+		}
+		return d.master, nil
+	}
+	err = d.endMedia()
+	if err != nil {
+		return nil, err
+	}
+	return d.media, nil
+}
+
+// 解析媒体列表
+func (d *Decoder) DecodeMedia() (*MediaPlayList, error) {
+	d.kind = MediaPlayListType
+	err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	err = d.endMedia()
+	if err != nil {
+		return nil, err
+	}
+	return d.media, nil
+}
+
+// 解析主列表
+func (d *Decoder) DecodeMaster() (*MasterPlayList, error) {
+	d.kind = MasterPlayListType
+	err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	err = d.endMaster()
+	if err != nil {
+		return nil, err
+	}
+	return d.master, nil
+}
+
+// 返回当前行的*ParseError
+func (d *Decoder) errorf(format string, args ...interface{}) error {
+	return &ParseError{Line: d.reader.Line(), Err: fmt.Errorf(format, args...)}
+}
+
+// 严格模式返回当前行的*ParseError，宽松模式记录一个Warning，返回nil，
+// fix是宽松模式下如何修复的说明，添加到Warning.Message后面
+func (d *Decoder) violation(fix, format string, args ...interface{}) error {
+	if d.mode == Strict {
+		return d.errorf(format, args...)
+	}
+	// format可能有%w
+	msg := fmt.Errorf(format, args...).Error()
+	if fix != "" {
+		msg += ", " + fix
+	}
+	d.warnf(0, "%s", msg)
+	return nil
+}
+
+// 记录当前行的Warning，column是在行中的列号
+func (d *Decoder) warnf(column int, format string, args ...interface{}) {
+	d.warnings = append(d.warnings, Warning{
+		Line:    d.reader.Line(),
+		Column:  column,
+		Tag:     string(d.tag),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// 记录当前tag的Warning，column是在<value>中的列号
+func (d *Decoder) warnValue(column int, format string, args ...interface{}) {
+	if column > 0 {
+		column += len(d.tag) + 1
+	}
+	d.warnf(column, format, args...)
+}
+
+// 宽松模式返回记录Warning的函数，严格模式返回nil
+func (d *Decoder) warn() warnFunc {
+	if d.mode == Strict {
+		return nil
+	}
+	return d.warnValue
+}
+
+// 记录err的Warning，tag被忽略
+func (d *Decoder) warnError(err error) {
+	var e *ParseError
+	if !errors.As(err, &e) {
+		e = &ParseError{Line: d.reader.Line(), Err: err}
+	}
+	d.warnings = append(d.warnings, Warning{
+		Line:    e.Line,
+		Column:  e.Column,
+		Tag:     e.Tag,
+		Message: e.Err.Error() + ", ignored",
+	})
+}
+
+// 返回当前行tag的*ParseError，如果err是*ParseError，
+// 它的Column是在<value>中的位置，转换成在行中的位置
+func (d *Decoder) tagError(tag []byte, err error) error {
+	column := len(tag) + 2
+	var e *ParseError
+	if errors.As(err, &e) {
+		if e.Column > 0 {
+			column += e.Column - 1
+		}
+		err = e.Err
+	}
+	return &ParseError{Line: d.reader.Line(), Column: column, Tag: string(tag), Err: err}
+}
+
+// 检查第一行是#EXTM3U，然后解析剩下的非空行
+func (d *Decoder) decode() error {
+	line, err := d.reader.ReadLine()
+	if err != nil {
+		if err == io.EOF {
+			if d.mode == Strict {
+				return &ParseError{Line: 1, Err: ErrMissingEXTM3U}
+			}
+			d.warnf(0, "%s", ErrMissingEXTM3U)
+			return nil
+		}
+		return err
+	}
+	if d.mode == Lenient && bytes.HasPrefix(line, utf8BOM) {
+		d.warnf(1, "UTF-8 BOM")
+		line = line[len(utf8BOM):]
+	}
+	if !bytes.Equal(line, tagEXTM3U) {
+		if d.mode == Strict {
+			return &ParseError{Line: 1, Err: ErrMissingEXTM3U}
+		}
+		if !bytes.Equal(bytes.TrimSpace(line), tagEXTM3U) {
+			d.warnf(0, "%s", ErrMissingEXTM3U)
+		}
+		// 第一行当作普通的行
+		err = d.decodeTrimLine(line)
+		if err != nil {
+			return err
+		}
+	}
+	for {
+		line, err = d.reader.ReadLine()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		err = d.decodeTrimLine(line)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// 检查行首尾的空白，然后解析非空行，RFC8216 4.1
+func (d *Decoder) decodeTrimLine(line []byte) error {
+	d.tag = nil
+	trim := bytes.TrimSpace(line)
+	if len(trim) != len(line) {
+		err := d.violation("trimmed", "whitespace at the beginning or end of line")
+		if err != nil {
+			return err
+		}
+	}
+	// 空行
+	if len(trim) == 0 {
+		return nil
+	}
+	err := d.decodeLine(trim)
+	d.index++
+	return err
+}
+
+// 设置列表的类型，一个列表不能同时有媒体列表和主列表的tag，RFC8216 4.3.4
+func (d *Decoder) setKind(tag []byte, kind PlayListType) error {
+	if d.kind == 0 {
+		d.kind = kind
+		return nil
+	}
+	if d.kind != kind {
+		return &ParseError{
+			Line: d.reader.Line(),
+			Tag:  string(tag),
+			Err:  fmt.Errorf("%w, not allowed in %s, RFC8216 4.3.4", ErrWrongPlaylistType, d.kind),
+		}
+	}
+	return nil
+}
+
+// 解析一行
+func (d *Decoder) decodeLine(line []byte) error {
+	// URI
+	if line[0] != '#' {
+		// 没有#EXT-X-DEFINE也要检查，所有的变量都是没有定义的
+		uri, err := substituteVariables(line, d.variables)
+		if err != nil {
+			err = d.violation("", "URI '%s' %w", line, err)
+			if err != nil {
+				return err
+			}
+		}
+		line = uri
+		if d.kind == MasterPlayListType {
+			return d.decodeStreamURI(line)
+		}
+		return d.decodeSegmentURI(line)
+	}
+	tag, value := ParseLine(line)
+	// #EXT-X-STREAM-INF后面只能是注释或者URI
+	if d.streamInf != nil && bytes.HasPrefix(tag, tagEXT) {
+		err := d.violation("ignored", "%s missing <URI>", TagEXT_X_STREAM_INF)
+		if err != nil {
+			return err
+		}
+		d.streamInf = nil
+	}
+	d.tag = tag
+	// 替换<quoted-string>中的变量，没有定义的变量保留原样
+	v := value
+	var varErr error
+	if !bytes.Equal(tag, tagEXT_X_DEFINE) && !bytes.Equal(tag, tagEXTINF) {
+		v, varErr = substituteQuotedVariables(value, d.variables)
+	}
+	ok, err := d.decodeKnownTag(tag, v)
+	if ok {
+		if err == nil && varErr != nil {
+			err = d.tagError(tag, varErr)
+		}
+		if err != nil {
+			return d.lenientError(err)
+		}
+		return nil
+	}
+	// 自定义的tag，不替换变量
+	ok, err = d.decodeCustomTag(tag, value)
+	if err != nil {
+		return d.lenientError(d.tagError(tag, err))
+	}
+	if ok {
+		return nil
+	}
+	// 不认识的tag和注释原样保存，SetUnknownTagError(true)时不接受#EXT-X-的tag
+	if d.unknownTag && bytes.HasPrefix(tag, tagEXT_X) {
+		return &ParseError{Line: d.reader.Line(), Tag: string(tag), Err: ErrUnknownTag}
+	}
+	d.decodeUnknown(line)
+	return nil
+}
+
+// 解析RFC8216的tag，返回是否认识tag，错误是*ParseError
+func (d *Decoder) decodeKnownTag(tag, value []byte) (bool, error) {
+	ok, err := d.decodeBasicTag(tag, value)
+	if !ok {
+		ok, err = d.decodeMediaTag(tag, value)
+		if ok {
+			if e := d.setKind(tag, MediaPlayListType); e != nil {
+				return true, e
+			}
+		} else {
+			ok, err = d.decodeMasterTag(tag, value)
+			if ok {
+				if e := d.setKind(tag, MasterPlayListType); e != nil {
+					// 媒体列表中的#EXT-X-STREAM-INF没有URI
+					d.streamInf = nil
+					return true, e
+				}
+			}
+		}
+	}
+	if err != nil {
+		return ok, d.tagError(tag, err)
+	}
+	return ok, nil
+}
+
+// 使用注册的TagHandler解析自定义tag，位置和不认识的tag一样，返回是否认识tag
+func (d *Decoder) decodeCustomTag(tag, value []byte) (bool, error) {
+	if d.tags == nil || !bytes.HasPrefix(tag, tagEXT) {
+		return false, nil
+	}
+	h := d.tags.Lookup(string(tag))
+	if h == nil {
+		return false, nil
+	}
+	t, err := h.Decode(value)
+	if err != nil {
+		return true, err
+	}
+	c := CustomTag{Index: d.index, Tag: t}
+	switch {
+	case d.kind == MasterPlayListType:
+		d.master.Tags = append(d.master.Tags, c)
+	case d.segment == nil && d.segments == 0:
+		d.media.Tags = append(d.media.Tags, c)
+		if d.kind == 0 {
+			d.master.Tags = append(d.master.Tags, c)
+		}
+	default:
+		seg := d.currentSegment()
+		seg.Tags = append(seg.Tags, c)
+	}
+	return true, nil
+}
+
+// 不认识的tag和注释，保存到出现的位置，片段之间的保存到下一个片段，
+// 第一个片段之前的保存到列表，最后一个片段之后的在endMedia中保存到列表
+func (d *Decoder) decodeUnknown(line []byte) {
+	s := UnknownLine{Index: d.index, Line: string(line)}
+	switch {
+	case d.kind == MasterPlayListType:
+		d.master.Unknown = append(d.master.Unknown, s)
+	case d.segment == nil && d.segments == 0:
+		d.media.Unknown = append(d.media.Unknown, s)
+		if d.kind == 0 {
+			d.master.Unknown = append(d.master.Unknown, s)
+		}
+	default:
+		seg := d.currentSegment()
+		seg.Unknown = append(seg.Unknown, s)
+	}
+}
+
+// 严格模式返回err，宽松模式记录Warning，返回nil
+func (d *Decoder) lenientError(err error) error {
+	if d.mode == Strict {
+		return err
+	}
+	d.warnError(err)
+	return nil
+}
+
+// 解析媒体列表和主列表都可以出现的tag，返回是否认识tag
+func (d *Decoder) decodeBasicTag(tag, value []byte) (bool, error) {
+	var err error
+	switch {
+	case bytes.Equal(tag, tagEXT_X_VERSION):
+		d.media.EXT_X_VERSION, err = ParseDecimalInteger(string(value))
+		d.master.EXT_X_VERSION = d.media.EXT_X_VERSION
+		d.setLine(TagEXT_X_VERSION)
+	case bytes.Equal(tag, tagEXT_X_INDEPENDENT_SEGMENTS):
+		d.media.EXT_X_INDEPENDENT_SEGMENTS = true
+		d.master.EXT_X_INDEPENDENT_SEGMENTS = true
+		d.setLine(TagEXT_X_INDEPENDENT_SEGMENTS)
+	case bytes.Equal(tag, tagEXT_X_DEFINE):
+		err = d.decodeEXT_X_DEFINE(value)
+	case bytes.Equal(tag, tagEXT_X_START):
+		var start *EXT_X_START
+		start, err = parseEXT_X_START(value, d.warn())
+		if err == nil {
+			start.Line = d.reader.Line()
+			d.media.EXT_X_START = start
+			d.master.EXT_X_START = start
+		}
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+// 解析#EXT-X-DEFINE，保存变量
+func (d *Decoder) decodeEXT_X_DEFINE(value []byte) error {
+	define, err := parseEXT_X_DEFINE(value, d.warn())
+	if err != nil {
+		return err
+	}
+	name := define.NAME
+	var v string
+	switch {
+	case define.IMPORT != "":
+		name = define.IMPORT
+		// 只能在媒体列表中使用
+		if d.kind == MasterPlayListType {
+			return attributeError(0, "IMPORT is not allowed in %s", d.kind)
+		}
+		if d.parent == nil {
+			return attributeError(0, "IMPORT '%s' requires parent master playlist", name)
+		}
+		var ok bool
+		v, ok = d.parent.Variables()[name]
+		if !ok {
+			return attributeError(0, "IMPORT '%s' is not defined in parent master playlist", name)
+		}
+	case define.QUERYPARAM != "":
+		name = define.QUERYPARAM
+		if d.url == nil {
+			return attributeError(0, "QUERYPARAM '%s' requires playlist URL", name)
+		}
+		q := d.url.Query()
+		if _, ok := q[name]; !ok {
+			return attributeError(0, "QUERYPARAM '%s' is not in playlist URL", name)
+		}
+		v = q.Get(name)
+	default:
+		v = define.VALUE
+	}
+	if _, ok := d.variables[name]; ok {
+		return attributeError(0, "duplicate variable '%s'", name)
+	}
+	if d.variables == nil {
+		d.variables = make(map[string]string)
+		d.media.variables = d.variables
+		d.master.variables = d.variables
+	}
+	d.variables[name] = v
+	define.Line = d.reader.Line()
+	d.media.EXT_X_DEFINE = append(d.media.EXT_X_DEFINE, *define)
+	d.master.EXT_X_DEFINE = append(d.master.EXT_X_DEFINE, *define)
+	return nil
+}
+
+// 解析媒体列表的tag，返回是否认识tag
+func (d *Decoder) decodeMediaTag(tag, value []byte) (bool, error) {
+	var err error
+	switch {
+	// media segment tags
+	case bytes.Equal(tag, tagEXTINF):
+		err = d.decodeEXTINF(value)
+	case bytes.Equal(tag, tagEXT_X_BYTERANGE):
+		var byteRange *EXT_X_BYTERANGE
+		byteRange, err = ParseByteRange(string(value))
+		if err == nil {
+			d.currentSegment().EXT_X_BYTERANGE = byteRange
+		}
+	case bytes.Equal(tag, tagEXT_X_DISCONTINUITY):
+		d.currentSegment().EXT_X_DISCONTINUITY = true
+	case bytes.Equal(tag, tagEXT_X_KEY):
+		var key *EXT_X_KEY
+		key, err = parseEXT_X_KEY(value, d.warn())
+		if err == nil {
+			key.Line = d.reader.Line()
+			d.keys = UpdateKeys(d.keys, key)
+			// 片段开始了，后面不认识的行属于片段
+			d.currentSegment()
+		}
+	case bytes.Equal(tag, tagEXT_X_MAP):
+		var xmap *EXT_X_MAP
+		xmap, err = parseEXT_X_MAP(value, d.warn())
+		if err == nil {
+			xmap.Line = d.reader.Line()
+			d.xmap = xmap
+			d.currentSegment()
+		}
+	case bytes.Equal(tag, tagEXT_X_PROGRAM_DATE_TIME):
+		var dateTime time.Time
+		dateTime, err = ParseDateTime(string(value))
+		if err == nil {
+			d.currentSegment().EXT_X_PROGRAM_DATE_TIME = dateTime
+		}
+	case bytes.Equal(tag, tagEXT_X_DATERANGE):
+		var dateRange *EXT_X_DATERANGE
+		dateRange, err = parseEXT_X_DATERANGE(value, d.warn())
+		if err == nil {
+			dateRange.Line = d.reader.Line()
+			s := d.currentSegment()
+			s.EXT_X_DATERANGE = append(s.EXT_X_DATERANGE, *dateRange)
+		}
+	case bytes.Equal(tag, tagEXT_X_GAP):
+		d.currentSegment().EXT_X_GAP = true
+	case bytes.Equal(tag, tagEXT_X_BITRATE):
+		var bitrate int64
+		bitrate, err = ParseDecimalInteger(string(value))
+		if err == nil {
+			d.bitrate = bitrate
+			d.currentSegment()
+		}
+	// media playlist tags
+	case bytes.Equal(tag, tagEXT_X_TARGETDURATION):
+		d.media.EXT_X_TARGETDURATION, err = ParseDecimalInteger(string(value))
+		d.targetDuration = err == nil
+		d.setLine(TagEXT_X_TARGETDURATION)
+	case bytes.Equal(tag, tagEXT_X_MEDIA_SEQUENCE):
+		d.media.EXT_X_MEDIA_SEQUENCE, err = ParseDecimalInteger(string(value))
+		d.setLine(TagEXT_X_MEDIA_SEQUENCE)
+	case bytes.Equal(tag, tagEXT_X_DISCONTINUITY_SEQUENCE):
+		d.media.EXT_X_DISCONTINUITY_SEQUENCE, err = ParseDecimalInteger(string(value))
+		d.setLine(TagEXT_X_DISCONTINUITY_SEQUENCE)
+	case bytes.Equal(tag, tagEXT_X_ENDLIST):
+		d.media.EXT_X_ENDLIST = true
+		d.setLine(TagEXT_X_ENDLIST)
+	case bytes.Equal(tag, tagEXT_X_PLAYLIST_TYPE):
+		d.media.EXT_X_PLAYLIST_TYPE, err = parseEnum(value, d.warn(), "EVENT", "VOD")
+		d.setLine(TagEXT_X_PLAYLIST_TYPE)
+	case bytes.Equal(tag, tagEXT_X_I_FRAMES_ONLY):
+		d.media.EXT_X_I_FRAMES_ONLY = true
+		d.setLine(TagEXT_X_I_FRAMES_ONLY)
+	// low-latency tags
+	case bytes.Equal(tag, tagEXT_X_PART):
+		var part *EXT_X_PART
+		part, err = parseEXT_X_PART(value, d.warn())
+		if err == nil {
+			part.Line = d.reader.Line()
+			s := d.currentSegment()
+			s.EXT_X_PART = append(s.EXT_X_PART, *part)
+		}
+	case bytes.Equal(tag, tagEXT_X_PART_INF):
+		var partInf *EXT_X_PART_INF
+		partInf, err = parseEXT_X_PART_INF(value, d.warn())
+		if err == nil {
+			partInf.Line = d.reader.Line()
+			d.media.EXT_X_PART_INF = partInf
+		}
+	case bytes.Equal(tag, tagEXT_X_SERVER_CONTROL):
+		var serverControl *EXT_X_SERVER_CONTROL
+		serverControl, err = parseEXT_X_SERVER_CONTROL(value, d.warn())
+		if err == nil {
+			serverControl.Line = d.reader.Line()
+			d.media.EXT_X_SERVER_CONTROL = serverControl
+		}
+	case bytes.Equal(tag, tagEXT_X_PRELOAD_HINT):
+		var hint *EXT_X_PRELOAD_HINT
+		hint, err = parseEXT_X_PRELOAD_HINT(value, d.warn())
+		if err == nil {
+			hint.Line = d.reader.Line()
+			d.media.EXT_X_PRELOAD_HINT = append(d.media.EXT_X_PRELOAD_HINT, *hint)
+		}
+	case bytes.Equal(tag, tagEXT_X_RENDITION_REPORT):
+		var report *EXT_X_RENDITION_REPORT
+		report, err = parseEXT_X_RENDITION_REPORT(value, d.warn())
+		if err == nil {
+			report.Line = d.reader.Line()
+			d.media.EXT_X_RENDITION_REPORT = append(d.media.EXT_X_RENDITION_REPORT, *report)
+		}
+	case bytes.Equal(tag, tagEXT_X_SKIP):
+		var skip *EXT_X_SKIP
+		skip, err = parseEXT_X_SKIP(value, d.warn())
+		if err == nil {
+			skip.Line = d.reader.Line()
+			d.media.EXT_X_SKIP = skip
+		}
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+// 记录列表中只有一个值的tag的行号，媒体列表和主列表使用同一个map
+func (d *Decoder) setLine(tag string) {
+	if d.media.Lines == nil {
+		d.media.Lines = make(map[string]int)
+		d.master.Lines = d.media.Lines
+	}
+	d.media.Lines[tag] = d.reader.Line()
+}
+
+// 返回正在解析的片段
+func (d *Decoder) currentSegment() *MediaSegment {
+	if d.segment == nil {
+		d.segment = new(MediaSegment)
+		// 列表开始的部分结束了，当前行是第一个片段的第0行
+		if d.segments == 0 {
+			d.index = 0
+		}
+	}
+	return d.segment
+}
+
+// #EXTINF:<duration>,[<title>]
+func (d *Decoder) decodeEXTINF(value []byte) error {
+	duration := value
+	var title []byte
+	i := bytes.IndexByte(value, ',')
+	if i < 0 {
+		if d.mode == Strict {
+			return fmt.Errorf("can't find ',' after <duration> '%s'", value)
+		}
+		d.warnValue(len(value)+1, "can't find ',' after <duration> '%s'", value)
+	} else {
+		duration = value[:i]
+		title = value[i+1:]
+	}
+	f, err := ParseDecimalFloat(string(duration))
+	if err != nil {
+		// NaN，Inf和负数，宽松模式使用0
+		if _, e := strconv.ParseFloat(string(duration), 64); e != nil {
+			return err
+		}
+		if err = d.violation("use duration 0", "%v", err); err != nil {
+			return err
+		}
+		f = 0
+	}
+	s := d.currentSegment()
+	s.EXTINF.DURATION = f
+	s.EXTINF.TITLE = string(title)
+	d.extinf = true
+	return nil
+}
+
+// 片段的URI，一个片段结束
+func (d *Decoder) decodeSegmentURI(line []byte) error {
+	if !d.extinf {
+		err := d.violation("use duration 0", "segment '%s' missing %s", line, TagEXTINF)
+		if err != nil {
+			return err
+		}
+	}
+	s := d.currentSegment()
+	s.URI = string(line)
+	s.Line = d.reader.Line()
+	s.EXT_X_KEY = d.keys
+	s.EXT_X_MAP = d.xmap
+	// #EXT-X-BITRATE不适用于有#EXT-X-BYTERANGE的片段
+	if s.EXT_X_BYTERANGE == nil {
+		s.EXT_X_BITRATE = d.bitrate
+	}
+	d.segment = nil
+	d.extinf = false
+	d.segments++
+	// 下一行是下一个片段的第0行
+	d.index = -1
+	if n := int64(math.Round(s.EXTINF.DURATION)); n > d.maxDuration {
+		d.maxDuration = n
+	}
+	if d.handler != nil {
+		return d.handleSegment(s)
+	}
+	d.media.MediaSegment = append(d.media.MediaSegment, *s)
+	return nil
+}
+
+// 解析完成后，检查媒体列表
+func (d *Decoder) endMedia() error {
+	d.tag = nil
+	if d.extinf {
+		err := d.violation("ignored", "%s missing <URI>", TagEXTINF)
+		if err != nil {
+			return err
+		}
+		d.extinf = false
+	}
+	// 最后一个片段之后的，没有URI的片段只保留自定义的tag，不认识的tag和注释，
+	// 还有正在生成的片段的#EXT-X-PART
+	if d.segment != nil {
+		d.media.TrailingTags = d.segment.Tags
+		d.media.TrailingUnknown = d.segment.Unknown
+		d.media.EXT_X_PART = d.segment.EXT_X_PART
+		d.segment = nil
+	}
+	// 四舍五入后的片段时长不能大于#EXT-X-TARGETDURATION，RFC8216 4.3.3.1
+	max := d.maxDuration
+	if !d.targetDuration {
+		err := d.violation(fmt.Sprintf("use %d", max), "missing %s", TagEXT_X_TARGETDURATION)
+		if err != nil {
+			return err
+		}
+		d.media.EXT_X_TARGETDURATION = max
+	} else if max > d.media.EXT_X_TARGETDURATION {
+		err := d.violation(fmt.Sprintf("use %d", max), "%s %d is less than segment duration %d", TagEXT_X_TARGETDURATION, d.media.EXT_X_TARGETDURATION, max)
+		if err != nil {
+			return err
+		}
+		d.media.EXT_X_TARGETDURATION = max
+	}
+	return nil
+}
+
+// 解析主列表的tag，返回是否认识tag
+func (d *Decoder) decodeMasterTag(tag, value []byte) (bool, error) {
+	var err error
+	switch {
+	case bytes.Equal(tag, tagEXT_X_MEDIA):
+		var media *EXT_X_MEDIA
+		media, err = parseEXT_X_MEDIA(value, d.warn())
+		if err == nil {
+			media.Line = d.reader.Line()
+			d.master.EXT_X_MEDIA = append(d.master.EXT_X_MEDIA, *media)
+		}
+	case bytes.Equal(tag, tagEXT_X_STREAM_INF):
+		d.streamInf, err = parseEXT_X_STREAM_INF(value, d.warn())
+		if err == nil {
+			d.streamInf.Line = d.reader.Line()
+		}
+	case bytes.Equal(tag, tagEXT_X_I_FRAME_STREAM_INF):
+		var iFrame *EXT_X_I_FRAME_STREAM_INF
+		iFrame, err = parseEXT_X_I_FRAME_STREAM_INF(value, d.warn())
+		if err == nil {
+			iFrame.Line = d.reader.Line()
+			d.master.EXT_X_I_FRAME_STREAM_INF = append(d.master.EXT_X_I_FRAME_STREAM_INF, *iFrame)
+		}
+	case bytes.Equal(tag, tagEXT_X_SESSION_DATA):
+		var data *EXT_X_SESSION_DATA
+		data, err = parseEXT_X_SESSION_DATA(value, d.warn())
+		if err == nil {
+			data.Line = d.reader.Line()
+			d.master.EXT_X_SESSION_DATA = append(d.master.EXT_X_SESSION_DATA, *data)
+		}
+	case bytes.Equal(tag, tagEXT_X_SESSION_KEY):
+		var key *EXT_X_KEY
+		key, err = parseEXT_X_KEY(value, d.warn())
+		if err == nil {
+			key.Line = d.reader.Line()
+			d.master.EXT_X_SESSION_KEY = key
+		}
+	case bytes.Equal(tag, tagEXT_X_CONTENT_STEERING):
+		var steering *EXT_X_CONTENT_STEERING
+		steering, err = parseEXT_X_CONTENT_STEERING(value, d.warn())
+		if err == nil {
+			steering.Line = d.reader.Line()
+			d.master.EXT_X_CONTENT_STEERING = steering
+		}
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+// #EXT-X-STREAM-INF后面的URI
+func (d *Decoder) decodeStreamURI(line []byte) error {
+	if d.streamInf == nil {
+		return d.violation("ignored", "URI '%s' missing %s", line, TagEXT_X_STREAM_INF)
+	}
+	d.streamInf.URI = string(line)
+	d.master.EXT_X_STREAM_INF = append(d.master.EXT_X_STREAM_INF, *d.streamInf)
+	d.streamInf = nil
+	return nil
+}
+
+// 解析完成后，检查主列表
+func (d *Decoder) endMaster() error {
+	d.tag = nil
+	if d.streamInf != nil {
+		err := d.violation("ignored", "%s missing <URI>", TagEXT_X_STREAM_INF)
+		if err != nil {
+			return err
+		}
+		d.streamInf = nil
+	}
+	return nil
+}
+
+// <enumerated-string>，必须是enums其中一个，warn不是nil时，可以是小写
+func parseEnum(value []byte, warn warnFunc, enums ...string) (string, error) {
+	for _, s := range enums {
+		if string(value) == s {
+			return s, nil
+		}
+	}
+	if warn != nil {
+		for _, s := range enums {
+			if strings.EqualFold(string(value), s) {
+				warn(1, "'%s' should be '%s'", value, s)
+				return s, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("invalid enumerated-string '%s'", value)
+}
+
+// METHOD=<method>,URI=<uri>,IV=<iv>,KEYFORMAT=<format>,KEYFORMATVERSIONS=<versions>
+func parseEXT_X_KEY(value []byte, warn warnFunc) (*EXT_X_KEY, error) {
+	tag := new(EXT_X_KEY)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	if tag.METHOD != "NONE" && tag.URI == "" {
+		return nil, attributeError(0, "missing URI")
+	}
+	return tag, nil
+}
+
+// URI=<uri>,BYTERANGE=<n>[@<o>]
+func parseEXT_X_MAP(value []byte, warn warnFunc) (*EXT_X_MAP, error) {
+	tag := new(EXT_X_MAP)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// ID=<id>,CLASS=<class>,START-DATE=<date>,...,X-<client-attribute>=<value>
+func parseEXT_X_DATERANGE(value []byte, warn warnFunc) (*EXT_X_DATERANGE, error) {
+	tag := new(EXT_X_DATERANGE)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// TYPE=<type>,URI=<uri>,GROUP-ID=<group-id>,LANGUAGE=<language>,...
+func parseEXT_X_MEDIA(value []byte, warn warnFunc) (*EXT_X_MEDIA, error) {
+	tag := new(EXT_X_MEDIA)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	if tag.TYPE == "CLOSED-CAPTIONS" {
+		if tag.URI != "" {
+			return nil, attributeError(0, "URI is not allowed when TYPE is CLOSED-CAPTIONS")
+		}
+		if tag.INSTREAM_ID == "" {
+			return nil, attributeError(0, "missing INSTREAM-ID")
+		}
+	}
+	return tag, nil
+}
+
+// BANDWIDTH=<n>,AVERAGE-BANDWIDTH=<n>,CODECS=<codecs>,RESOLUTION=<w>x<h>,...
+func parseEXT_X_STREAM_INF(value []byte, warn warnFunc) (*EXT_X_STREAM_INF, error) {
+	tag := new(EXT_X_STREAM_INF)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// BANDWIDTH=<n>,AVERAGE-BANDWIDTH=<n>,CODECS=<codecs>,...,URI=<uri>
+func parseEXT_X_I_FRAME_STREAM_INF(value []byte, warn warnFunc) (*EXT_X_I_FRAME_STREAM_INF, error) {
+	tag := new(EXT_X_I_FRAME_STREAM_INF)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// DATA-ID=<id>,VALUE=<value>,URI=<uri>,LANGUAGE=<language>
+func parseEXT_X_SESSION_DATA(value []byte, warn warnFunc) (*EXT_X_SESSION_DATA, error) {
+	tag := new(EXT_X_SESSION_DATA)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	if (tag.VALUE == "") == (tag.URI == "") {
+		return nil, attributeError(0, "must contain either VALUE or URI")
+	}
+	return tag, nil
+}
+
+// SERVER-URI=<URI>,PATHWAY-ID=<pathway-id>
+func parseEXT_X_CONTENT_STEERING(value []byte, warn warnFunc) (*EXT_X_CONTENT_STEERING, error) {
+	tag := new(EXT_X_CONTENT_STEERING)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// NAME=<name>,VALUE=<value>或者IMPORT=<name>或者QUERYPARAM=<name>
+func parseEXT_X_DEFINE(value []byte, warn warnFunc) (*EXT_X_DEFINE, error) {
+	tag := new(EXT_X_DEFINE)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	name := ""
+	for _, s := range []string{tag.NAME, tag.IMPORT, tag.QUERYPARAM} {
+		if s != "" {
+			n++
+			name = s
+		}
+	}
+	if n != 1 {
+		return nil, attributeError(0, "must contain exactly one of NAME, IMPORT or QUERYPARAM")
+	}
+	if !isVariableName(name) {
+		return nil, attributeError(0, "invalid variable name '%s'", name)
+	}
+	if tag.VALUE != "" && tag.NAME == "" {
+		return nil, attributeError(0, "VALUE is only allowed with NAME")
+	}
+	return tag, nil
+}
+
+// TIME-OFFSET=<s>,PRECISE=<YES|NO>
+func parseEXT_X_START(value []byte, warn warnFunc) (*EXT_X_START, error) {
+	tag := new(EXT_X_START)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// URI=<uri>,DURATION=<s>,INDEPENDENT=YES,BYTERANGE=<n>[@<o>],GAP=YES
+func parseEXT_X_PART(value []byte, warn warnFunc) (*EXT_X_PART, error) {
+	tag := new(EXT_X_PART)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// PART-TARGET=<s>
+func parseEXT_X_PART_INF(value []byte, warn warnFunc) (*EXT_X_PART_INF, error) {
+	tag := new(EXT_X_PART_INF)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// CAN-SKIP-UNTIL=<s>,CAN-SKIP-DATERANGES=YES,HOLD-BACK=<s>,PART-HOLD-BACK=<s>,CAN-BLOCK-RELOAD=YES
+func parseEXT_X_SERVER_CONTROL(value []byte, warn warnFunc) (*EXT_X_SERVER_CONTROL, error) {
+	tag := new(EXT_X_SERVER_CONTROL)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// TYPE=<PART|MAP>,URI=<uri>,BYTERANGE-START=<n>,BYTERANGE-LENGTH=<n>
+func parseEXT_X_PRELOAD_HINT(value []byte, warn warnFunc) (*EXT_X_PRELOAD_HINT, error) {
+	tag := new(EXT_X_PRELOAD_HINT)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// URI=<uri>,LAST-MSN=<n>,LAST-PART=<n>
+func parseEXT_X_RENDITION_REPORT(value []byte, warn warnFunc) (*EXT_X_RENDITION_REPORT, error) {
+	tag := new(EXT_X_RENDITION_REPORT)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// SKIPPED-SEGMENTS=<n>,RECENTLY-REMOVED-DATERANGES=<ids>
+func parseEXT_X_SKIP(value []byte, warn warnFunc) (*EXT_X_SKIP, error) {
+	tag := new(EXT_X_SKIP)
+	err := unmarshalAttributes(value, tag, warn)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
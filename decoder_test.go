@@ -0,0 +1,360 @@
+package m3u8
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// 输出p，出错时结束测试
+func encodeString(t *testing.T, p PlayList) string {
+	t.Helper()
+	var b strings.Builder
+	_, err := p.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+const testMediaPlayList = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:10
+#EXT-X-MEDIA-SEQUENCE:100
+#EXT-X-DISCONTINUITY-SEQUENCE:2
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/key",IV=0x0123456789ABCDEF0123456789ABCDEF
+#EXT-X-MAP:URI="init.mp4",BYTERANGE="720@0"
+#EXT-X-PROGRAM-DATE-TIME:2020-01-02T03:04:05.678Z
+#EXTINF:9.009,first
+0.m4s
+#EXT-X-BYTERANGE:1000@720
+#EXTINF:10,
+1.m4s
+#EXT-X-DISCONTINUITY
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:8.5,
+2.m4s
+#EXT-X-ENDLIST
+`
+
+func TestParseMediaPlayList(t *testing.T) {
+	p, err := ParseMediaPlayList(strings.NewReader(testMediaPlayList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.EXT_X_VERSION != 7 || p.EXT_X_TARGETDURATION != 10 || p.EXT_X_MEDIA_SEQUENCE != 100 ||
+		p.EXT_X_DISCONTINUITY_SEQUENCE != 2 || p.EXT_X_PLAYLIST_TYPE != "VOD" || !p.EXT_X_ENDLIST {
+		t.Fatalf("playlist tags: %+v", p)
+	}
+	if len(p.MediaSegment) != 3 {
+		t.Fatalf("got %d segments, want 3", len(p.MediaSegment))
+	}
+	s := &p.MediaSegment[0]
+	if s.URI != "0.m4s" || s.EXTINF.DURATION != 9.009 || s.EXTINF.TITLE != "first" || s.Line != 11 {
+		t.Fatalf("segment 0: %+v", s)
+	}
+	if len(s.EXT_X_KEY) != 1 || s.EXT_X_KEY[0].METHOD != "AES-128" || len(s.EXT_X_KEY[0].IV) != 16 || s.EXT_X_KEY[0].Line != 7 {
+		t.Fatalf("segment 0 key: %+v", s.EXT_X_KEY)
+	}
+	if s.EXT_X_MAP == nil || s.EXT_X_MAP.URI != "init.mp4" || s.EXT_X_MAP.BYTERANGE.N != 720 {
+		t.Fatalf("segment 0 map: %+v", s.EXT_X_MAP)
+	}
+	if !s.EXT_X_PROGRAM_DATE_TIME.Equal(time.Date(2020, 1, 2, 3, 4, 5, 678e6, time.UTC)) {
+		t.Fatalf("segment 0 date time: %v", s.EXT_X_PROGRAM_DATE_TIME)
+	}
+	// KEY和MAP会应用到后面的片段
+	s = &p.MediaSegment[1]
+	if len(s.EXT_X_KEY) != 1 || s.EXT_X_KEY[0] != p.MediaSegment[0].EXT_X_KEY[0] || s.EXT_X_MAP != p.MediaSegment[0].EXT_X_MAP {
+		t.Fatalf("segment 1 key and map: %+v %+v", s.EXT_X_KEY, s.EXT_X_MAP)
+	}
+	if s.EXT_X_BYTERANGE == nil || s.EXT_X_BYTERANGE.N != 1000 || s.EXT_X_BYTERANGE.O == nil || *s.EXT_X_BYTERANGE.O != 720 {
+		t.Fatalf("segment 1 byte range: %+v", s.EXT_X_BYTERANGE)
+	}
+	s = &p.MediaSegment[2]
+	if !s.EXT_X_DISCONTINUITY || s.EXT_X_KEY != nil {
+		t.Fatalf("segment 2: %+v", s)
+	}
+}
+
+func TestParseMediaPlayListRoundTrip(t *testing.T) {
+	p, err := ParseMediaPlayList(strings.NewReader(testMediaPlayList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := encodeString(t, p)
+	p2, err := ParseMediaPlayList(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("%v\n%s", err, s)
+	}
+	if s2 := encodeString(t, p2); s2 != s {
+		t.Fatalf("got\n%s\nwant\n%s", s2, s)
+	}
+}
+
+// 每个KEYFORMAT一个key，METHOD=NONE清除所有的key
+func TestParseMediaPlayListKeys(t *testing.T) {
+	src := `#EXTM3U
+#EXT-X-VERSION:5
+#EXT-X-TARGETDURATION:4
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://1",KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="data:text/plain;base64,AAAA",KEYFORMAT="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed",KEYFORMATVERSIONS="1"
+#EXTINF:4,
+0.ts
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://2",KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"
+#EXTINF:4,
+1.ts
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:4,
+2.ts
+#EXT-X-KEY:METHOD=AES-128,URI="k"
+#EXTINF:4,
+3.ts
+`
+	p, err := ParseMediaPlayList(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uris := func(keys []*EXT_X_KEY) []string {
+		var s []string
+		for _, k := range keys {
+			s = append(s, k.URI)
+		}
+		return s
+	}
+	want := [][]string{
+		{"skd://1", "data:text/plain;base64,AAAA"},
+		{"skd://2", "data:text/plain;base64,AAAA"},
+		nil,
+		{"k"},
+	}
+	for i, w := range want {
+		got := uris(p.MediaSegment[i].EXT_X_KEY)
+		if strings.Join(got, " ") != strings.Join(w, " ") {
+			t.Fatalf("segment %d: got %v, want %v", i, got, w)
+		}
+	}
+	// 前一个片段的key不能被修改
+	if p.MediaSegment[0].EXT_X_KEY[0].URI != "skd://1" {
+		t.Fatal("segment 0 key is modified")
+	}
+	// 只输出变化的key
+	if s := encodeString(t, p); s != src {
+		t.Fatalf("got\n%s\nwant\n%s", s, src)
+	}
+}
+
+func TestUpdateKeys(t *testing.T) {
+	k1 := &EXT_X_KEY{METHOD: "AES-128", URI: "1"}
+	k2 := &EXT_X_KEY{METHOD: "SAMPLE-AES", URI: "2", KEYFORMAT: "com.apple.streamingkeydelivery"}
+	k3 := &EXT_X_KEY{METHOD: "AES-128", URI: "3", KEYFORMAT: DefaultKeyFormat}
+	keys := UpdateKeys(nil, k1)
+	keys = UpdateKeys(keys, k2)
+	if len(keys) != 2 || keys[0] != k1 || keys[1] != k2 {
+		t.Fatalf("got %v", keys)
+	}
+	// 没有KEYFORMAT就是identity
+	keys2 := UpdateKeys(keys, k3)
+	if len(keys2) != 2 || keys2[0] != k3 || keys2[1] != k2 || keys[0] != k1 {
+		t.Fatalf("got %v", keys2)
+	}
+	if keys := UpdateKeys(keys2, &EXT_X_KEY{METHOD: "NONE"}); keys != nil {
+		t.Fatalf("got %v, want nil", keys)
+	}
+}
+
+const testMasterPlayList = `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-SESSION-DATA:DATA-ID="com.example.title",VALUE="title",LANGUAGE="en"
+#EXT-X-SESSION-KEY:METHOD=SAMPLE-AES,URI="skd://key",KEYFORMAT="com.apple.streamingkeydelivery"
+#EXT-X-MEDIA:TYPE=AUDIO,URI="audio/en.m3u8",GROUP-ID="aac",LANGUAGE="en",NAME="English",DEFAULT=YES,AUTOSELECT=YES,CHANNELS="2"
+#EXT-X-MEDIA:TYPE=AUDIO,URI="audio/fr.m3u8",GROUP-ID="aac",LANGUAGE="fr",NAME="Français",AUTOSELECT=YES,CHANNELS="2"
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,AVERAGE-BANDWIDTH=1000000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=1280x720,FRAME-RATE=29.970,AUDIO="aac",CLOSED-CAPTIONS=NONE
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2560000,RESOLUTION=1920x1080,AUDIO="aac"
+1080p.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=86000,RESOLUTION=1280x720,URI="720p-iframe.m3u8"
+`
+
+func TestParseMasterPlayList(t *testing.T) {
+	p, err := ParseMasterPlayList(strings.NewReader(testMasterPlayList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.EXT_X_VERSION != 6 || !p.EXT_X_INDEPENDENT_SEGMENTS {
+		t.Fatalf("playlist tags: %+v", p)
+	}
+	if len(p.EXT_X_SESSION_DATA) != 1 || p.EXT_X_SESSION_DATA[0].DATA_ID != "com.example.title" || p.EXT_X_SESSION_DATA[0].LANGUAGE != "en" {
+		t.Fatalf("session data: %+v", p.EXT_X_SESSION_DATA)
+	}
+	if p.EXT_X_SESSION_KEY == nil || p.EXT_X_SESSION_KEY.URI != "skd://key" {
+		t.Fatalf("session key: %+v", p.EXT_X_SESSION_KEY)
+	}
+	if len(p.EXT_X_MEDIA) != 2 {
+		t.Fatalf("got %d media, want 2", len(p.EXT_X_MEDIA))
+	}
+	m := &p.EXT_X_MEDIA[1]
+	if m.TYPE != "AUDIO" || m.GROUP_ID != "aac" || m.NAME != "Français" || m.DEFAULT || !m.AUTOSELECT || m.CHANNELS != "2" {
+		t.Fatalf("media 1: %+v", m)
+	}
+	if len(p.EXT_X_STREAM_INF) != 2 {
+		t.Fatalf("got %d stream inf, want 2", len(p.EXT_X_STREAM_INF))
+	}
+	s := &p.EXT_X_STREAM_INF[0]
+	if s.BANDWIDTH != 1280000 || s.AVERAGE_BANDWIDTH != 1000000 || s.CODECS != "avc1.4d401f,mp4a.40.2" ||
+		s.RESOLUTION != (Resolution{Width: 1280, Height: 720}) || s.FRAME_RATE != 29.97 ||
+		s.AUDIO != "aac" || s.CLOSED_CAPTIONS != "NONE" || s.URI != "720p.m3u8" {
+		t.Fatalf("stream inf 0: %+v", s)
+	}
+	if p.EXT_X_STREAM_INF[1].URI != "1080p.m3u8" {
+		t.Fatalf("stream inf 1: %+v", p.EXT_X_STREAM_INF[1])
+	}
+	if len(p.EXT_X_I_FRAME_STREAM_INF) != 1 || p.EXT_X_I_FRAME_STREAM_INF[0].URI != "720p-iframe.m3u8" {
+		t.Fatalf("i-frame stream inf: %+v", p.EXT_X_I_FRAME_STREAM_INF)
+	}
+}
+
+func TestParseMasterPlayListStreamInfWithoutURI(t *testing.T) {
+	src := "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\n"
+	if _, err := ParseMasterPlayList(strings.NewReader(src)); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	p, err := Decode(strings.NewReader(testMediaPlayList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*MediaPlayList); !ok {
+		t.Fatalf("got %T, want *MediaPlayList", p)
+	}
+	p, err = Decode(strings.NewReader(testMasterPlayList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*MasterPlayList); !ok {
+		t.Fatalf("got %T, want *MasterPlayList", p)
+	}
+	// 媒体列表和主列表的tag不能混在一起
+	for _, s := range []string{
+		"#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:1,\na.ts\n#EXT-X-STREAM-INF:BANDWIDTH=1\nb.m3u8\n",
+		"#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\nb.m3u8\n#EXTINF:1,\na.ts\n",
+	} {
+		if _, err := Decode(strings.NewReader(s)); err == nil {
+			t.Fatalf("%q: want error", s)
+		}
+	}
+}
+
+// #EXTINF的时长不能是NaN，Inf或者负数
+func TestDecodeInvalidDuration(t *testing.T) {
+	for _, v := range []string{"NaN", "Inf", "-Inf", "-1"} {
+		s := "#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:" + v + ",\na.ts\n"
+		if _, err := Decode(strings.NewReader(s)); err == nil {
+			t.Fatalf("%s: want error", v)
+		}
+		d := NewDecoder(strings.NewReader(s), Lenient)
+		p, err := d.DecodeMedia()
+		if err != nil {
+			t.Fatalf("%s: %v", v, err)
+		}
+		if len(d.Warnings()) != 1 || d.Warnings()[0].Line != 3 {
+			t.Fatalf("%s: warnings %v", v, d.Warnings())
+		}
+		if p.MediaSegment[0].EXTINF.DURATION != 0 {
+			t.Fatalf("%s: got duration %v, want 0", v, p.MediaSegment[0].EXTINF.DURATION)
+		}
+	}
+}
+
+// 宽松模式修复不符合RFC8216的地方，每个修复记录一个Warning
+func TestDecodeLenient(t *testing.T) {
+	src := "\xEF\xBB\xBF#EXTM3U\n" +
+		"#EXT-X-TARGETDURATION:5\n" +
+		"#EXT-X-KEY:METHOD=AES-128, URI=\"k\",URI=\"j\",\n" +
+		"#EXTINF:6,\n" +
+		"  a.ts  \n" +
+		"b.ts\n" +
+		"#EXT-X-FOO:1\n" +
+		"#EXTINF:4,\n" +
+		"c.ts\n"
+	if _, err := Decode(strings.NewReader(src)); err == nil {
+		t.Fatal("want error in strict mode")
+	}
+	d := NewDecoder(strings.NewReader(src), Lenient)
+	p, err := d.DecodeMedia()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"line 1, column 1: UTF-8 BOM",
+		"line 3, column 27: #EXT-X-KEY whitespace before attribute",
+		"line 3, column 36: #EXT-X-KEY duplicate attribute 'URI', ignored",
+		"line 3, column 43: #EXT-X-KEY trailing ','",
+		"line 5: whitespace at the beginning or end of line, trimmed",
+		"line 6: segment 'b.ts' missing #EXTINF, use duration 0",
+		"line 9: #EXT-X-TARGETDURATION 5 is less than segment duration 6, use 6",
+	}
+	warnings := d.Warnings()
+	if len(warnings) != len(want) {
+		t.Fatalf("got warnings %v", warnings)
+	}
+	for i, w := range warnings {
+		if w.String() != want[i] {
+			t.Fatalf("warning %d: got %q, want %q", i, w.String(), want[i])
+		}
+	}
+	if p.EXT_X_TARGETDURATION != 6 || len(p.MediaSegment) != 3 {
+		t.Fatalf("got target duration %d, %d segments", p.EXT_X_TARGETDURATION, len(p.MediaSegment))
+	}
+	if p.MediaSegment[0].URI != "a.ts" || p.MediaSegment[0].EXT_X_KEY[0].URI != "k" {
+		t.Fatalf("segment 0: %+v", p.MediaSegment[0])
+	}
+	// 宽松模式下不认识的tag原样保存，没有Warning
+	if u := p.MediaSegment[2].Unknown; len(u) != 1 || u[0].Line != "#EXT-X-FOO:1" {
+		t.Fatalf("segment 2 unknown: %v", u)
+	}
+}
+
+// #EXT-X-BITRATE应用到后面的片段，有#EXT-X-BYTERANGE的片段除外，输出时只在变化的时候输出
+func TestParseMediaPlayListBitrate(t *testing.T) {
+	src := "#EXTM3U\n" +
+		"#EXT-X-VERSION:4\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXT-X-BITRATE:1000\n" +
+		"#EXTINF:10,\n" +
+		"a.ts\n" +
+		"#EXTINF:10,\n" +
+		"b.ts\n" +
+		"#EXT-X-BYTERANGE:100@0\n" +
+		"#EXTINF:10,\n" +
+		"c.ts\n" +
+		"#EXTINF:10,\n" +
+		"d.ts\n" +
+		"#EXT-X-BITRATE:2000\n" +
+		"#EXTINF:10,\n" +
+		"e.ts\n"
+	p, err := ParseMediaPlayList(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{1000, 1000, 0, 1000, 2000}
+	if len(p.MediaSegment) != len(want) {
+		t.Fatalf("got %d segments, want %d", len(p.MediaSegment), len(want))
+	}
+	for i, b := range want {
+		if p.MediaSegment[i].EXT_X_BITRATE != b {
+			t.Fatalf("segment %d: got %d, want %d", i, p.MediaSegment[i].EXT_X_BITRATE, b)
+		}
+	}
+	if s := encodeString(t, p); s != src {
+		t.Fatalf("got\n%s\nwant\n%s", s, src)
+	}
+	// 有#EXT-X-BYTERANGE的片段的EXT_X_BITRATE不输出
+	p.MediaSegment[2].EXT_X_BITRATE = 3000
+	if s := encodeString(t, p); s != src {
+		t.Fatalf("got\n%s\nwant\n%s", s, src)
+	}
+}
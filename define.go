@@ -0,0 +1,115 @@
+package m3u8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// 变量引用的开始和结束，{$<name>}
+var (
+	variableBegin = []byte("{$")
+	variableEnd   = byte('}')
+)
+
+// 返回列表的变量，name:value。解析出来的列表包括IMPORT和QUERYPARAM的值，
+// 否则只有NAME和VALUE定义的变量
+func (p *MediaPlayList) Variables() map[string]string {
+	if p.variables != nil {
+		return p.variables
+	}
+	return defineVariables(p.EXT_X_DEFINE)
+}
+
+// 返回列表的变量，name:value。解析出来的列表包括QUERYPARAM的值，
+// 否则只有NAME和VALUE定义的变量
+func (p *MasterPlayList) Variables() map[string]string {
+	if p.variables != nil {
+		return p.variables
+	}
+	return defineVariables(p.EXT_X_DEFINE)
+}
+
+// 返回NAME和VALUE定义的变量
+func defineVariables(defines []EXT_X_DEFINE) map[string]string {
+	m := make(map[string]string)
+	for i := 0; i < len(defines); i++ {
+		if defines[i].NAME != "" {
+			m[defines[i].NAME] = defines[i].VALUE
+		}
+	}
+	return m
+}
+
+// 变量名只能是[a-z]，[A-Z]，[0-9]，'-'和'_'
+func isVariableName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') &&
+			!(c >= '0' && c <= '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// 替换s中所有的{$<name>}，没有定义的变量保留原样，返回第一个没有定义的错误
+func substituteVariables(s []byte, variables map[string]string) ([]byte, error) {
+	i := bytes.Index(s, variableBegin)
+	if i < 0 {
+		return s, nil
+	}
+	var err error
+	b := make([]byte, 0, len(s))
+	for i >= 0 {
+		b = append(b, s[:i]...)
+		s = s[i:]
+		j := bytes.IndexByte(s, variableEnd)
+		if j < 0 {
+			break
+		}
+		name := string(s[len(variableBegin):j])
+		value, ok := variables[name]
+		if ok && isVariableName(name) {
+			b = append(b, value...)
+		} else {
+			b = append(b, s[:j+1]...)
+			if err == nil && isVariableName(name) {
+				err = fmt.Errorf("%w '%s'", ErrUndefinedVariable, name)
+			}
+		}
+		s = s[j+1:]
+		i = bytes.Index(s, variableBegin)
+	}
+	return append(b, s...), err
+}
+
+// 替换<attribute-list>中<quoted-string>的变量
+func substituteQuotedVariables(value []byte, variables map[string]string) ([]byte, error) {
+	if !bytes.Contains(value, variableBegin) {
+		return value, nil
+	}
+	var err error
+	b := make([]byte, 0, len(value))
+	for {
+		i := bytes.IndexByte(value, '"')
+		if i < 0 {
+			return append(b, value...), err
+		}
+		b = append(b, value[:i+1]...)
+		value = value[i+1:]
+		j := bytes.IndexByte(value, '"')
+		if j < 0 {
+			return append(b, value...), err
+		}
+		s, e := substituteVariables(value[:j], variables)
+		if err == nil {
+			err = e
+		}
+		b = append(b, s...)
+		b = append(b, '"')
+		value = value[j+1:]
+	}
+}
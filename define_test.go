@@ -0,0 +1,101 @@
+package m3u8
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDefine(t *testing.T) {
+	src := "#EXTM3U\n" +
+		"#EXT-X-VERSION:11\n" +
+		"#EXT-X-DEFINE:NAME=\"host\",VALUE=\"example.com\"\n" +
+		"#EXT-X-DEFINE:QUERYPARAM=\"token\"\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXTINF:10,\n" +
+		"https://{$host}/a.ts?t={$token}\n"
+	u, err := url.Parse("https://example.com/live.m3u8?token=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDecoder(strings.NewReader(src), Strict)
+	d.SetURL(u)
+	p, err := d.DecodeMedia()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := p.MediaSegment[0].URI; s != "https://example.com/a.ts?t=abc" {
+		t.Fatalf("got URI %s", s)
+	}
+	// QUERYPARAM需要版本11
+	if v := p.MinimumVersion(); v != 11 {
+		t.Fatalf("got minimum version %d, want 11", v)
+	}
+	// 不输出#EXT-X-DEFINE时，不需要版本8以上
+	var b strings.Builder
+	e := NewEncoder(&b)
+	e.SetAutoVersion(true)
+	e.SetDefines(false)
+	p.EXT_X_VERSION = 0
+	if err := e.Encode(p); err != nil {
+		t.Fatal(err)
+	}
+	want := "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\nhttps://example.com/a.ts?t=abc\n"
+	if b.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestDefineImport(t *testing.T) {
+	master, err := ParseMasterPlayList(strings.NewReader("#EXTM3U\n" +
+		"#EXT-X-VERSION:8\n" +
+		"#EXT-X-DEFINE:NAME=\"cdn\",VALUE=\"c.example\"\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1\n" +
+		"https://{$cdn}/v.m3u8\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := master.EXT_X_STREAM_INF[0].URI; s != "https://c.example/v.m3u8" {
+		t.Fatalf("got URI %s", s)
+	}
+	d := NewDecoder(strings.NewReader("#EXTM3U\n"+
+		"#EXT-X-VERSION:8\n"+
+		"#EXT-X-DEFINE:IMPORT=\"cdn\"\n"+
+		"#EXT-X-TARGETDURATION:10\n"+
+		"#EXTINF:10,\n"+
+		"https://{$cdn}/a.ts\n"), Strict)
+	d.SetParent(master)
+	p, err := d.DecodeMedia()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := p.MediaSegment[0].URI; s != "https://c.example/a.ts" {
+		t.Fatalf("got URI %s", s)
+	}
+}
+
+// 没有#EXT-X-DEFINE也要检查变量
+func TestUndefinedVariable(t *testing.T) {
+	src := "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\n{$x}.ts\n"
+	_, err := Decode(strings.NewReader(src))
+	if !errors.Is(err, ErrUndefinedVariable) {
+		t.Fatalf("got %v, want ErrUndefinedVariable", err)
+	}
+	var e *ParseError
+	if !errors.As(err, &e) || e.Line != 4 {
+		t.Fatalf("got %v, want line 4", err)
+	}
+	d := NewDecoder(strings.NewReader(src), Lenient)
+	p, err := d.DecodeMedia()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Warnings()) != 1 || d.Warnings()[0].Line != 4 {
+		t.Fatalf("got warnings %v", d.Warnings())
+	}
+	// 宽松模式保留原来的值
+	if s := p.MediaSegment[0].URI; s != "{$x}.ts" {
+		t.Fatalf("got URI %s", s)
+	}
+}
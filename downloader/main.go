@@ -9,7 +9,6 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"strings"
 	"sync"
 
 	"github.com/qq51529210/m3u8"
@@ -86,46 +85,20 @@ func (d *downloader) downloadList() ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	// 读取每一行
-	ts := make([]string, 0)
-	reader := m3u8.NewReader(file, nil)
-	for {
-		line, err := reader.ReadLine()
-		if err != nil {
-			if err == io.EOF {
-				return ts, nil
-			}
-			return nil, err
-		}
-		name := string(line)
-		// 只要片段 #EXTINF:10.043356,
-		if !strings.HasPrefix(name, m3u8.TagEXTINF) {
-			continue
-		}
-		// :10.043356,
-		name = name[len(m3u8.TagEXTINF):]
-		// ':'
-		if name[0] == ':' {
-			name = name[1:]
-			// duration
-			i := strings.IndexByte(name, ',')
-			if i > 0 {
-				name = name[i+1:]
-				// title 0000.ts
-				if len(name) == 0 {
-					// 再读一行
-					line, err = reader.ReadLine()
-					if err != nil {
-						return nil, err
-					}
-					name = string(line)
-				}
-				ts = append(ts, name)
-				continue
-			}
-		}
-		return nil, fmt.Errorf("invalid tag '%s'", name)
+	// 解析列表，网上的列表经常有不规范的地方，使用宽松模式
+	decoder := m3u8.NewDecoder(file, m3u8.Lenient)
+	list, err := decoder.DecodeMedia()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range decoder.Warnings() {
+		fmt.Println(w)
+	}
+	ts := make([]string, 0, len(list.MediaSegment))
+	for i := 0; i < len(list.MediaSegment); i++ {
+		ts = append(ts, list.MediaSegment[i].URI)
 	}
+	return ts, nil
 }
 
 func (d *downloader) downloadTS(url string) error {
@@ -0,0 +1,408 @@
+package m3u8
+
+import (
+	"bytes"
+	"io"
+)
+
+// 累计写入的字节数，出错以后不再累计。
+// 输出列表的一部分（列表的开始，片段等）时，按照Index把自定义的tag和不认识的行放回原来的位置
+type writeCounter struct {
+	n       int64
+	err     error
+	tags    []CustomTag   // 这部分还没有输出的自定义的tag
+	unknown []UnknownLine // 这部分还没有输出的不认识的行
+	index   int           // 这部分已经输出的行数
+}
+
+func (c *writeCounter) add(n int, err error) {
+	if c.err != nil {
+		return
+	}
+	c.n += int64(n)
+	c.err = err
+}
+
+// 输出列表
+type Encoder struct {
+	writer      Writer
+	autoVersion bool
+	noDefines   bool
+}
+
+// 创建Encoder，writer是接收输出的数据
+func NewEncoder(writer io.Writer) *Encoder {
+	e := new(Encoder)
+	e.writer.SetWriter(writer)
+	return e
+}
+
+// 设置是否自动计算#EXT-X-VERSION，如果列表的EXT_X_VERSION小于MinimumVersion()，
+// 输出MinimumVersion()，列表本身不会被修改
+func (e *Encoder) SetAutoVersion(auto bool) {
+	e.autoVersion = auto
+}
+
+// 设置是否输出#EXT-X-DEFINE，默认输出。解析出来的列表已经替换了变量，
+// 可以不输出，这样不支持版本8（QUERYPARAM是11）的客户端也可以使用
+func (e *Encoder) SetDefines(defines bool) {
+	e.noDefines = !defines
+}
+
+// 按照RFC8216的顺序输出整个列表，p是*MediaPlayList或者*MasterPlayList
+func (e *Encoder) Encode(p PlayList) error {
+	var err error
+	switch l := p.(type) {
+	case *MediaPlayList:
+		version := l.EXT_X_VERSION
+		if e.autoVersion {
+			version = autoVersion(version, minimumVersion(e.versionRequirements(l.VersionRequirements())))
+		}
+		_, err = l.encode(&e.writer, version, !e.noDefines)
+	case *MasterPlayList:
+		version := l.EXT_X_VERSION
+		if e.autoVersion {
+			version = autoVersion(version, minimumVersion(e.versionRequirements(l.VersionRequirements())))
+		}
+		_, err = l.encode(&e.writer, version, !e.noDefines)
+	default:
+		_, err = p.WriteTo(e.writer.writer)
+	}
+	return err
+}
+
+// 不输出#EXT-X-DEFINE时，去掉它需要的版本
+func (e *Encoder) versionRequirements(r []VersionRequirement) []VersionRequirement {
+	if !e.noDefines {
+		return r
+	}
+	rr := r[:0:0]
+	for i := 0; i < len(r); i++ {
+		if r[i].Tag != TagEXT_X_DEFINE {
+			rr = append(rr, r[i])
+		}
+	}
+	return rr
+}
+
+// 返回需要输出的版本，版本1可以不输出
+func autoVersion(version, minimum int64) int64 {
+	if minimum > 1 && minimum > version {
+		return minimum
+	}
+	return version
+}
+
+// 使用w输出#EXT-X-DEFINE
+func (c *writeCounter) addDefines(w *Writer, defines []EXT_X_DEFINE) {
+	for i := 0; i < len(defines); i++ {
+		c.line(w)
+		c.add(w.EXT_X_DEFINE(&defines[i]))
+	}
+}
+
+// 开始输出新的一部分
+func (c *writeCounter) begin(tags []CustomTag, unknown []UnknownLine) {
+	c.tags = tags
+	c.unknown = unknown
+	c.index = 0
+}
+
+// 输出RFC8216的一行之前调用，先输出位置在这一行之前的自定义的tag和不认识的行
+func (c *writeCounter) line(w *Writer) {
+	c.insert(w, false)
+	c.index++
+}
+
+// 输出这部分剩下的自定义的tag和不认识的行
+func (c *writeCounter) end(w *Writer) {
+	c.insert(w, true)
+}
+
+// 按照Index的顺序输出自定义的tag和不认识的行，Index一样的tag在前面，
+// all表示输出所有剩下的，否则只输出Index不大于当前行的
+func (c *writeCounter) insert(w *Writer, all bool) {
+	for c.err == nil {
+		tag := len(c.tags) > 0
+		if len(c.unknown) > 0 && (!tag || c.unknown[0].Index < c.tags[0].Index) {
+			if !all && c.unknown[0].Index > c.index {
+				return
+			}
+			c.add(w.RawLine(c.unknown[0].Line))
+			c.unknown = c.unknown[1:]
+		} else if tag {
+			if !all && c.tags[0].Index > c.index {
+				return
+			}
+			n := w.written
+			err := c.tags[0].Tag.Encode(w)
+			c.add(int(w.written-n), err)
+			c.tags = c.tags[1:]
+		} else {
+			return
+		}
+		c.index++
+	}
+}
+
+// 实现io.WriterTo，按照RFC8216的顺序输出整个媒体列表
+func (p *MediaPlayList) WriteTo(writer io.Writer) (int64, error) {
+	return p.encode(NewWriter(writer), p.EXT_X_VERSION, true)
+}
+
+// 使用w输出整个媒体列表，version是输出的#EXT-X-VERSION，0不输出，
+// defines是否输出#EXT-X-DEFINE
+func (p *MediaPlayList) encode(w *Writer, version int64, defines bool) (int64, error) {
+	c := new(writeCounter)
+	// basic tags
+	c.add(w.EXTM3U())
+	c.begin(p.Tags, p.Unknown)
+	if version != 0 {
+		c.line(w)
+		c.add(w.EXT_X_VERSION(version))
+	}
+	if defines {
+		c.addDefines(w, p.EXT_X_DEFINE)
+	}
+	// media playlist tags
+	c.line(w)
+	c.add(w.EXT_X_TARGETDURATION(p.EXT_X_TARGETDURATION))
+	if p.EXT_X_SERVER_CONTROL != nil {
+		c.line(w)
+		// 没有属性的不输出，但是占用解析时的位置
+		if !emptyEXT_X_SERVER_CONTROL(p.EXT_X_SERVER_CONTROL) {
+			c.add(w.EXT_X_SERVER_CONTROL(p.EXT_X_SERVER_CONTROL))
+		}
+	}
+	if p.EXT_X_PART_INF != nil {
+		c.line(w)
+		c.add(w.EXT_X_PART_INF(p.EXT_X_PART_INF))
+	}
+	if p.EXT_X_MEDIA_SEQUENCE != 0 {
+		c.line(w)
+		c.add(w.EXT_X_MEDIA_SEQUENCE(p.EXT_X_MEDIA_SEQUENCE))
+	}
+	if p.EXT_X_DISCONTINUITY_SEQUENCE != 0 {
+		c.line(w)
+		c.add(w.EXT_X_DISCONTINUITY_SEQUENCE(p.EXT_X_DISCONTINUITY_SEQUENCE))
+	}
+	if p.EXT_X_PLAYLIST_TYPE != "" {
+		c.line(w)
+		c.add(w.EXT_X_PLAYLIST_TYPE(p.EXT_X_PLAYLIST_TYPE))
+	}
+	if p.EXT_X_I_FRAMES_ONLY {
+		c.line(w)
+		c.add(w.EXT_X_I_FRAMES_ONLY())
+	}
+	if p.EXT_X_INDEPENDENT_SEGMENTS {
+		c.line(w)
+		c.add(w.EXT_X_INDEPENDENT_SEGMENTS())
+	}
+	if p.EXT_X_START != nil {
+		c.line(w)
+		c.add(w.EXT_X_START(p.EXT_X_START))
+	}
+	// 跳过的片段
+	if p.EXT_X_SKIP != nil {
+		c.line(w)
+		c.add(w.EXT_X_SKIP(p.EXT_X_SKIP))
+	}
+	c.end(w)
+	// media segments
+	var keys []*EXT_X_KEY
+	var xmap *EXT_X_MAP
+	var bitrate int64
+	for i := 0; i < len(p.MediaSegment) && c.err == nil; i++ {
+		s := &p.MediaSegment[i]
+		c.begin(s.Tags, s.Unknown)
+		if s.EXT_X_DISCONTINUITY {
+			c.line(w)
+			c.add(w.EXT_X_DISCONTINUITY())
+		}
+		c.addKeys(w, keys, s.EXT_X_KEY)
+		keys = s.EXT_X_KEY
+		if s.EXT_X_MAP != nil && !equalEXT_X_MAP(xmap, s.EXT_X_MAP) {
+			c.line(w)
+			c.add(w.EXT_X_MAP(s.EXT_X_MAP))
+			xmap = s.EXT_X_MAP
+		}
+		if !s.EXT_X_PROGRAM_DATE_TIME.IsZero() {
+			c.line(w)
+			c.add(w.EXT_X_PROGRAM_DATE_TIME(s.EXT_X_PROGRAM_DATE_TIME))
+		}
+		for j := 0; j < len(s.EXT_X_DATERANGE); j++ {
+			c.line(w)
+			c.add(w.EXT_X_DATERANGE(&s.EXT_X_DATERANGE[j]))
+		}
+		// 和前一个片段不一样才输出，0不能取消前面的
+		if s.EXT_X_BITRATE != 0 && s.EXT_X_BITRATE != bitrate && s.EXT_X_BYTERANGE == nil {
+			c.line(w)
+			c.add(w.EXT_X_BITRATE(s.EXT_X_BITRATE))
+			bitrate = s.EXT_X_BITRATE
+		}
+		if s.EXT_X_GAP {
+			c.line(w)
+			c.add(w.EXT_X_GAP())
+		}
+		for j := 0; j < len(s.EXT_X_PART); j++ {
+			c.line(w)
+			c.add(w.EXT_X_PART(&s.EXT_X_PART[j]))
+		}
+		if s.EXT_X_BYTERANGE != nil {
+			c.line(w)
+			c.add(w.EXT_X_BYTERANGE(s.EXT_X_BYTERANGE))
+		}
+		c.line(w)
+		c.add(w.EXTINF(&s.EXTINF))
+		// URI是片段的最后一行
+		c.end(w)
+		c.add(w.URI(s.URI))
+	}
+	c.begin(p.TrailingTags, p.TrailingUnknown)
+	// 还没有完成的片段
+	for i := 0; i < len(p.EXT_X_PART); i++ {
+		c.line(w)
+		c.add(w.EXT_X_PART(&p.EXT_X_PART[i]))
+	}
+	for i := 0; i < len(p.EXT_X_PRELOAD_HINT); i++ {
+		c.line(w)
+		c.add(w.EXT_X_PRELOAD_HINT(&p.EXT_X_PRELOAD_HINT[i]))
+	}
+	if p.EXT_X_ENDLIST {
+		c.line(w)
+		c.add(w.EXT_X_ENDLIST())
+	}
+	for i := 0; i < len(p.EXT_X_RENDITION_REPORT); i++ {
+		c.line(w)
+		c.add(w.EXT_X_RENDITION_REPORT(&p.EXT_X_RENDITION_REPORT[i]))
+	}
+	c.end(w)
+	return c.n, c.err
+}
+
+// 实现io.WriterTo，按照RFC8216的顺序输出整个主列表
+func (p *MasterPlayList) WriteTo(writer io.Writer) (int64, error) {
+	return p.encode(NewWriter(writer), p.EXT_X_VERSION, true)
+}
+
+// 使用w输出整个主列表，version是输出的#EXT-X-VERSION，0不输出，
+// defines是否输出#EXT-X-DEFINE
+func (p *MasterPlayList) encode(w *Writer, version int64, defines bool) (int64, error) {
+	c := new(writeCounter)
+	// basic tags
+	c.add(w.EXTM3U())
+	c.begin(p.Tags, p.Unknown)
+	if version != 0 {
+		c.line(w)
+		c.add(w.EXT_X_VERSION(version))
+	}
+	if defines {
+		c.addDefines(w, p.EXT_X_DEFINE)
+	}
+	// master playlist tags
+	if p.EXT_X_INDEPENDENT_SEGMENTS {
+		c.line(w)
+		c.add(w.EXT_X_INDEPENDENT_SEGMENTS())
+	}
+	if p.EXT_X_START != nil {
+		c.line(w)
+		c.add(w.EXT_X_START(p.EXT_X_START))
+	}
+	for i := 0; i < len(p.EXT_X_SESSION_DATA); i++ {
+		c.line(w)
+		c.add(w.EXT_X_SESSION_DATA(&p.EXT_X_SESSION_DATA[i]))
+	}
+	if p.EXT_X_SESSION_KEY != nil {
+		c.line(w)
+		c.add(w.EXT_X_SESSION_KEY(p.EXT_X_SESSION_KEY))
+	}
+	if p.EXT_X_CONTENT_STEERING != nil {
+		c.line(w)
+		c.add(w.EXT_X_CONTENT_STEERING(p.EXT_X_CONTENT_STEERING))
+	}
+	for i := 0; i < len(p.EXT_X_MEDIA); i++ {
+		c.line(w)
+		c.add(w.EXT_X_MEDIA(&p.EXT_X_MEDIA[i]))
+	}
+	// #EXT-X-STREAM-INF和URI之间也可以有注释
+	for i := 0; i < len(p.EXT_X_STREAM_INF); i++ {
+		c.line(w)
+		c.add(w.streamInf(&p.EXT_X_STREAM_INF[i]))
+		c.line(w)
+		c.add(w.URI(p.EXT_X_STREAM_INF[i].URI))
+	}
+	for i := 0; i < len(p.EXT_X_I_FRAME_STREAM_INF); i++ {
+		c.line(w)
+		c.add(w.EXT_X_I_FRAME_STREAM_INF(&p.EXT_X_I_FRAME_STREAM_INF[i]))
+	}
+	c.end(w)
+	return c.n, c.err
+}
+
+// 输出和前一个片段prev不一样的#EXT-X-KEY，
+// prev中的KEYFORMAT在keys中没有了，先输出METHOD=NONE清除所有的key
+func (c *writeCounter) addKeys(w *Writer, prev, keys []*EXT_X_KEY) {
+	for i := 0; i < len(prev); i++ {
+		if indexKeyFormat(keys, prev[i].KeyFormat()) < 0 {
+			c.line(w)
+			c.add(w.EXT_X_KEY(&EXT_X_KEY{METHOD: "NONE"}))
+			prev = nil
+			break
+		}
+	}
+	for i := 0; i < len(keys); i++ {
+		j := indexKeyFormat(prev, keys[i].KeyFormat())
+		if j < 0 || !equalEXT_X_KEY(prev[j], keys[i]) {
+			c.line(w)
+			c.add(w.EXT_X_KEY(keys[i]))
+		}
+	}
+}
+
+// 比较两个EXT_X_KEY是否一样
+func equalEXT_X_KEY(k1, k2 *EXT_X_KEY) bool {
+	if k1 == k2 {
+		return true
+	}
+	if k1 == nil || k2 == nil {
+		return false
+	}
+	return k1.METHOD == k2.METHOD &&
+		k1.URI == k2.URI &&
+		bytes.Equal(k1.IV, k2.IV) &&
+		k1.KEYFORMAT == k2.KEYFORMAT &&
+		k1.KEYFORMATVERSIONS == k2.KEYFORMATVERSIONS
+}
+
+// 是否没有任何属性，Line不算
+func emptyEXT_X_SERVER_CONTROL(s *EXT_X_SERVER_CONTROL) bool {
+	return *s == EXT_X_SERVER_CONTROL{Line: s.Line}
+}
+
+// 比较两个EXT_X_MAP是否一样
+func equalEXT_X_MAP(m1, m2 *EXT_X_MAP) bool {
+	if m1 == m2 {
+		return true
+	}
+	if m1 == nil || m2 == nil {
+		return false
+	}
+	return m1.URI == m2.URI && equalEXT_X_BYTERANGE(m1.BYTERANGE, m2.BYTERANGE)
+}
+
+// 比较两个EXT_X_BYTERANGE是否一样
+func equalEXT_X_BYTERANGE(b1, b2 *EXT_X_BYTERANGE) bool {
+	if b1 == b2 {
+		return true
+	}
+	if b1 == nil || b2 == nil {
+		return false
+	}
+	if b1.N != b2.N {
+		return false
+	}
+	if b1.O == nil || b2.O == nil {
+		return b1.O == b2.O
+	}
+	return *b1.O == *b2.O
+}
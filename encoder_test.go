@@ -0,0 +1,193 @@
+package m3u8
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMediaPlayListWriteTo(t *testing.T) {
+	o := int64(0)
+	p := &MediaPlayList{
+		EXT_X_VERSION:        4,
+		EXT_X_TARGETDURATION: 10,
+		EXT_X_PLAYLIST_TYPE:  "EVENT",
+		MediaSegment: []MediaSegment{
+			{EXTINF: EXTINF{DURATION: 9.5, TITLE: "a"}, URI: "a.ts", EXT_X_BYTERANGE: &EXT_X_BYTERANGE{N: 100, O: &o}},
+			{EXTINF: EXTINF{DURATION: 10}, URI: "b.ts", EXT_X_DISCONTINUITY: true},
+		},
+		EXT_X_ENDLIST: true,
+	}
+	want := `#EXTM3U
+#EXT-X-VERSION:4
+#EXT-X-TARGETDURATION:10
+#EXT-X-PLAYLIST-TYPE:EVENT
+#EXT-X-BYTERANGE:100@0
+#EXTINF:9.5,a
+a.ts
+#EXT-X-DISCONTINUITY
+#EXTINF:10,
+b.ts
+#EXT-X-ENDLIST
+`
+	var b strings.Builder
+	n, err := p.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", b.String(), want)
+	}
+	if n != int64(len(want)) {
+		t.Fatalf("got n %d, want %d", n, len(want))
+	}
+}
+
+func TestMasterPlayListRoundTrip(t *testing.T) {
+	p, err := ParseMasterPlayList(strings.NewReader(testMasterPlayList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := encodeString(t, p); s != testMasterPlayList {
+		t.Fatalf("got\n%s\nwant\n%s", s, testMasterPlayList)
+	}
+}
+
+// 每次WriteTo使用自己的Writer，可以同时输出同一个列表
+func TestWriteToConcurrent(t *testing.T) {
+	media, err := ParseMediaPlayList(strings.NewReader(testMediaPlayList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, err := ParseMasterPlayList(strings.NewReader(testMasterPlayList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []PlayList{media, master} {
+		want := encodeString(t, p)
+		var wg sync.WaitGroup
+		errs := make(chan string, 8)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var b strings.Builder
+				p.WriteTo(&b)
+				if b.String() != want {
+					errs <- b.String()
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for s := range errs {
+			t.Fatalf("got\n%s\nwant\n%s", s, want)
+		}
+	}
+}
+
+// testdata中的列表是输出的格式，解析以后再输出，应该一模一样
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no testdata")
+	}
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := Decode(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := encodeString(t, p); s != string(b) {
+				t.Fatalf("got\n%s\nwant\n%s", s, b)
+			}
+		})
+	}
+}
+
+// 不认识的tag和注释输出在原来的位置
+func TestUnknownLinePosition(t *testing.T) {
+	for _, src := range []string{
+		"#EXTM3U\n" +
+			"# header comment\n" +
+			"#EXT-X-VERSION:3\n" +
+			"#EXT-X-TARGETDURATION:10\n" +
+			"#EXT-X-KEY:METHOD=AES-128,URI=\"k\"\n" +
+			"#EXT-X-PROGRAM-DATE-TIME:2020-01-02T03:04:05.000Z\n" +
+			"#EXT-X-CUE-OUT:30\n" +
+			"#EXTINF:10,\n" +
+			"# between EXTINF and URI\n" +
+			"a.ts\n" +
+			"#EXT-X-CUE-IN\n" +
+			"#EXTINF:10,\n" +
+			"b.ts\n" +
+			"#EXT-X-ENDLIST\n" +
+			"# trailing comment\n",
+		"#EXTM3U\n" +
+			"#EXT-X-VERSION:3\n" +
+			"# before media\n" +
+			"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"a\",NAME=\"a\",DEFAULT=YES,AUTOSELECT=YES\n" +
+			"#EXT-X-STREAM-INF:BANDWIDTH=1,AUDIO=\"a\"\n" +
+			"# between STREAM-INF and URI\n" +
+			"a.m3u8\n" +
+			"# last line\n",
+	} {
+		d := NewDecoder(strings.NewReader(src), Lenient)
+		p, err := d.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Warnings()) != 0 {
+			t.Fatalf("warnings %v", d.Warnings())
+		}
+		if s := encodeString(t, p); s != src {
+			t.Fatalf("got\n%s\nwant\n%s", s, src)
+		}
+	}
+}
+
+// 没有属性的#EXT-X-SERVER-CONTROL不输出，Writer返回错误
+func TestEmptyServerControl(t *testing.T) {
+	p := &MediaPlayList{
+		EXT_X_VERSION:        3,
+		EXT_X_TARGETDURATION: 10,
+		EXT_X_SERVER_CONTROL: &EXT_X_SERVER_CONTROL{},
+		Unknown:              []UnknownLine{{Index: 3, Line: "# comment"}},
+		MediaSegment:         []MediaSegment{{EXTINF: EXTINF{DURATION: 10}, URI: "a.ts"}},
+	}
+	want := "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"# comment\n" +
+		"#EXTINF:10,\n" +
+		"a.ts\n"
+	if s := encodeString(t, p); s != want {
+		t.Fatalf("got\n%s\nwant\n%s", s, want)
+	}
+	var b bytes.Buffer
+	w := NewWriter(&b)
+	if _, err := w.EXT_X_SERVER_CONTROL(&EXT_X_SERVER_CONTROL{}); err == nil {
+		t.Fatal("want error")
+	}
+	if b.Len() != 0 {
+		t.Fatalf("got %q", b.String())
+	}
+	// 之后的输出不受影响
+	if _, err := w.EXT_X_SERVER_CONTROL(&EXT_X_SERVER_CONTROL{CAN_BLOCK_RELOAD: true}); err != nil {
+		t.Fatal(err)
+	}
+	if s := "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES\n"; b.String() != s {
+		t.Fatalf("got %q, want %q", b.String(), s)
+	}
+}
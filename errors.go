@@ -0,0 +1,69 @@
+package m3u8
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// 第一行不是#EXTM3U
+	ErrMissingEXTM3U = errors.New("missing " + TagEXTM3U)
+	// 不认识的#EXT-X-的tag，并且没有注册TagHandler，只有Decoder.SetUnknownTagError(true)时返回
+	ErrUnknownTag = errors.New("unknown tag")
+	// tag不能出现在这种类型的列表中，比如媒体列表中的#EXT-X-STREAM-INF
+	ErrWrongPlaylistType = errors.New("wrong playlist type")
+	// <attribute-list>格式错误，或者属性的值错误，或者缺少必须的属性
+	ErrMalformedAttribute = errors.New("malformed attribute")
+	// URI或者<quoted-string>中的{$<name>}没有使用#EXT-X-DEFINE定义
+	ErrUndefinedVariable = errors.New("undefined variable")
+)
+
+// 解析错误，可以使用errors.As获取，Err可以使用errors.Is判断
+type ParseError struct {
+	Line   int    // 行号，从1开始，0表示不知道
+	Column int    // 列号，从1开始，0表示不知道
+	Tag    string // 出错的tag，可能是空
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	s := ""
+	if e.Line > 0 {
+		s = fmt.Sprintf("line %d", e.Line)
+		if e.Column > 0 {
+			s += fmt.Sprintf(", column %d", e.Column)
+		}
+		s += ": "
+	} else if e.Column > 0 {
+		s = fmt.Sprintf("column %d: ", e.Column)
+	}
+	if e.Tag != "" {
+		s += e.Tag + " "
+	}
+	return s + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// 返回包装了ErrMalformedAttribute的错误，column是属性在<attribute-list>中的列号
+func attributeError(column int, format string, args ...interface{}) error {
+	return &ParseError{
+		Column: column,
+		Err:    fmt.Errorf("%w: %s", ErrMalformedAttribute, fmt.Sprintf(format, args...)),
+	}
+}
+
+// 宽松模式下，修复的不符合RFC8216的地方
+type Warning struct {
+	Line    int    // 行号，从1开始，0表示不知道
+	Column  int    // 列号，从1开始，0表示不知道
+	Tag     string // 相关的tag，可能是空
+	Message string
+}
+
+func (w Warning) String() string {
+	e := ParseError{Line: w.Line, Column: w.Column, Tag: w.Tag, Err: errors.New(w.Message)}
+	return e.Error()
+}
@@ -0,0 +1,75 @@
+package m3u8
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	for _, c := range []struct {
+		src    string
+		err    error
+		line   int
+		column int
+		tag    string
+	}{
+		{"#EXT-X-VERSION:3\n", ErrMissingEXTM3U, 1, 0, ""},
+		{"#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:1,\na.ts\n#EXT-X-STREAM-INF:BANDWIDTH=1\nb.m3u8\n", ErrWrongPlaylistType, 5, 0, TagEXT_X_STREAM_INF},
+		{"#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\nb.m3u8\n#EXTINF:1,\na.ts\n", ErrWrongPlaylistType, 4, 0, TagEXTINF},
+		{"#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1,CODECS=\"a\nb.m3u8\n", ErrMalformedAttribute, 2, 31, TagEXT_X_STREAM_INF},
+		{"#EXTM3U\n#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"a\",NAME=\"b\",DEFAULT=MAYBE\n", ErrMalformedAttribute, 2, 47, TagEXT_X_MEDIA},
+		{"#EXTM3U\n#EXT-X-MEDIA:TYPE=AUDIO,NAME=\"b\"\n", ErrMalformedAttribute, 2, 14, TagEXT_X_MEDIA},
+		{"#EXTM3U\n#EXT-X-TARGETDURATION:x\n", nil, 2, 23, TagEXT_X_TARGETDURATION},
+	} {
+		_, err := Decode(strings.NewReader(c.src))
+		var e *ParseError
+		if !errors.As(err, &e) {
+			t.Fatalf("%q: got %v, want *ParseError", c.src, err)
+		}
+		if c.err != nil && !errors.Is(err, c.err) {
+			t.Fatalf("%q: got %v, want %v", c.src, err, c.err)
+		}
+		if e.Line != c.line || e.Column != c.column || e.Tag != c.tag {
+			t.Fatalf("%q: got line %d column %d tag %q, want %d %d %q", c.src, e.Line, e.Column, e.Tag, c.line, c.column, c.tag)
+		}
+	}
+}
+
+func TestUnknownTag(t *testing.T) {
+	src := "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-FOO:1\n#EXTINF:10,\na.ts\n"
+	// 默认两种模式都保存到Unknown中
+	for _, mode := range []ParseMode{Strict, Lenient} {
+		p, err := NewDecoder(strings.NewReader(src), mode).DecodeMedia()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u := p.Unknown; len(u) != 1 || u[0].Line != "#EXT-X-FOO:1" {
+			t.Fatalf("mode %d: got %v", mode, u)
+		}
+	}
+	d := NewDecoder(strings.NewReader(src), Strict)
+	d.SetUnknownTagError(true)
+	_, err := d.DecodeMedia()
+	var e *ParseError
+	if !errors.As(err, &e) || !errors.Is(err, ErrUnknownTag) || e.Line != 3 || e.Tag != "#EXT-X-FOO" {
+		t.Fatalf("got %v, want %v at line 3", err, ErrUnknownTag)
+	}
+}
+
+func TestParseErrorString(t *testing.T) {
+	err := errors.New("x")
+	for _, c := range []struct {
+		e    ParseError
+		want string
+	}{
+		{ParseError{Err: err}, "x"},
+		{ParseError{Line: 2, Err: err}, "line 2: x"},
+		{ParseError{Line: 2, Column: 5, Tag: TagEXTINF, Err: err}, "line 2, column 5: #EXTINF x"},
+		{ParseError{Column: 5, Err: err}, "column 5: x"},
+	} {
+		if s := c.e.Error(); s != c.want {
+			t.Fatalf("got %q, want %q", s, c.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package m3u8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Interstitial的CLASS，draft-pantos-hls-rfc8216bis Appendix D
+const InterstitialClass = "com.apple.hls.interstitial"
+
+// #EXT-X-DATERANGE的CUE，draft-pantos-hls-rfc8216bis 4.4.5.1
+const (
+	CuePre  = "PRE"
+	CuePost = "POST"
+	CueOnce = "ONCE"
+)
+
+// Interstitial使用的X-属性
+const (
+	attrAssetURI     = "X-ASSET-URI"
+	attrAssetList    = "X-ASSET-LIST"
+	attrResumeOffset = "X-RESUME-OFFSET"
+	attrPlayoutLimit = "X-PLAYOUT-LIMIT"
+	attrSnap         = "X-SNAP"
+	attrRestrict     = "X-RESTRICT"
+)
+
+// CLASS是InterstitialClass的#EXT-X-DATERANGE的X-属性，
+// X_ASSET_URI和X_ASSET_LIST只能有一个
+type Interstitial struct {
+	X_ASSET_URI     string   // 广告的媒体列表或者主列表
+	X_ASSET_LIST    string   // JSON的asset list
+	X_RESUME_OFFSET *float64 // 结束后从主内容的哪里开始，nil表示DURATION，<decimal-floating-point>不能是负数
+	X_PLAYOUT_LIMIT *float64 // 最多播放的时长，不能是负数
+	X_SNAP          []string // OUT，IN
+	X_RESTRICT      []string // SKIP，JUMP
+}
+
+// 是否是Interstitial
+func (r *EXT_X_DATERANGE) IsInterstitial() bool {
+	return r.CLASS == InterstitialClass
+}
+
+// 返回CUE的值
+func (r *EXT_X_DATERANGE) Cues() []string {
+	return splitList(r.CUE)
+}
+
+// 返回X_CLIENT_ATTRIBUTE中Interstitial的属性，CLASS不是InterstitialClass返回nil
+func (r *EXT_X_DATERANGE) Interstitial() (*Interstitial, error) {
+	if !r.IsInterstitial() {
+		return nil, nil
+	}
+	a := r.X_CLIENT_ATTRIBUTE
+	i := new(Interstitial)
+	i.X_ASSET_URI, _ = a.Get(attrAssetURI)
+	i.X_ASSET_LIST, _ = a.Get(attrAssetList)
+	s, _ := a.Get(attrSnap)
+	i.X_SNAP = splitList(s)
+	s, _ = a.Get(attrRestrict)
+	i.X_RESTRICT = splitList(s)
+	var err error
+	i.X_RESUME_OFFSET, err = parseClientFloat(a, attrResumeOffset)
+	if err != nil {
+		return nil, err
+	}
+	i.X_PLAYOUT_LIMIT, err = parseClientFloat(a, attrPlayoutLimit)
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// 设置CLASS为InterstitialClass，替换X_CLIENT_ATTRIBUTE中Interstitial的属性，
+// 其他的X-属性不变
+func (r *EXT_X_DATERANGE) SetInterstitial(i *Interstitial) error {
+	if (i.X_ASSET_URI == "") == (i.X_ASSET_LIST == "") {
+		return fmt.Errorf("must contain either %s or %s", attrAssetURI, attrAssetList)
+	}
+	if i.X_RESUME_OFFSET != nil && *i.X_RESUME_OFFSET < 0 {
+		return fmt.Errorf("%s must not be negative", attrResumeOffset)
+	}
+	if i.X_PLAYOUT_LIMIT != nil && *i.X_PLAYOUT_LIMIT < 0 {
+		return fmt.Errorf("%s must not be negative", attrPlayoutLimit)
+	}
+	r.CLASS = InterstitialClass
+	a := &r.X_CLIENT_ATTRIBUTE
+	setClientAttribute(a, attrAssetURI, ClientString, i.X_ASSET_URI)
+	setClientAttribute(a, attrAssetList, ClientString, i.X_ASSET_LIST)
+	setClientAttribute(a, attrResumeOffset, ClientFloat, formatClientFloat(i.X_RESUME_OFFSET))
+	setClientAttribute(a, attrPlayoutLimit, ClientFloat, formatClientFloat(i.X_PLAYOUT_LIMIT))
+	setClientAttribute(a, attrSnap, ClientString, strings.Join(i.X_SNAP, ","))
+	setClientAttribute(a, attrRestrict, ClientString, strings.Join(i.X_RESTRICT, ","))
+	return nil
+}
+
+// 解析<decimal-floating-point>的X-属性，没有返回nil
+func parseClientFloat(a ClientAttributes, name string) (*float64, error) {
+	s, ok := a.Get(name)
+	if !ok {
+		return nil, nil
+	}
+	f, err := ParseDecimalFloat(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s %v", name, err)
+	}
+	return &f, nil
+}
+
+func formatClientFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return FormatDecimalFloat(*f)
+}
+
+// value是空时删除
+func setClientAttribute(a *ClientAttributes, name string, t ClientValueType, value string) {
+	if value == "" {
+		a.Delete(name)
+	} else {
+		a.Set(name, t, value)
+	}
+}
+
+// 用','分开的列表，空返回nil
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,99 @@
+// Interstitial的X-ASSET-LIST指向的JSON，draft-pantos-hls-rfc8216bis Appendix D.3。
+// 客户端在需要播放的时候请求，按顺序播放ASSETS
+package interstitial
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// asset list的Content-Type
+const ContentType = "application/json"
+
+// ASSETS是空
+var ErrEmptyAssets = errors.New("empty ASSETS")
+
+// asset list
+type AssetList struct {
+	ASSETS       []Asset      `json:"ASSETS"`
+	SKIP_CONTROL *SkipControl `json:"SKIP-CONTROL,omitempty"`
+}
+
+// 一个广告，URI是媒体列表或者主列表
+type Asset struct {
+	URI      string  `json:"URI"`
+	DURATION float64 `json:"DURATION"` // 秒
+}
+
+// 客户端可以跳过的时间段
+type SkipControl struct {
+	OFFSET   float64  `json:"OFFSET"`             // 从开始播放到可以跳过的时长
+	DURATION *float64 `json:"DURATION,omitempty"` // 可以跳过的时长，nil表示直到结束
+	LABEL_ID string   `json:"LABEL-ID,omitempty"` // 跳过按钮的文字，对应主列表#EXT-X-SESSION-DATA的DATA-ID
+}
+
+// 返回使用assets的AssetList
+func NewAssetList(assets ...Asset) *AssetList {
+	return &AssetList{ASSETS: assets}
+}
+
+// 解析r中的JSON，并检查
+func Parse(r io.Reader) (*AssetList, error) {
+	l := new(AssetList)
+	err := json.NewDecoder(r).Decode(l)
+	if err != nil {
+		return nil, err
+	}
+	err = l.Check()
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// 检查ASSETS和SKIP-CONTROL
+func (l *AssetList) Check() error {
+	if len(l.ASSETS) < 1 {
+		return ErrEmptyAssets
+	}
+	for i := 0; i < len(l.ASSETS); i++ {
+		a := &l.ASSETS[i]
+		if a.URI == "" {
+			return fmt.Errorf("ASSETS[%d] missing URI", i)
+		}
+		if a.DURATION < 0 || math.IsNaN(a.DURATION) || math.IsInf(a.DURATION, 0) {
+			return fmt.Errorf("ASSETS[%d] invalid DURATION %v", i, a.DURATION)
+		}
+	}
+	if c := l.SKIP_CONTROL; c != nil {
+		if c.OFFSET < 0 {
+			return fmt.Errorf("SKIP-CONTROL invalid OFFSET %v", c.OFFSET)
+		}
+		if c.DURATION != nil && *c.DURATION < 0 {
+			return fmt.Errorf("SKIP-CONTROL invalid DURATION %v", *c.DURATION)
+		}
+	}
+	return nil
+}
+
+// 所有ASSETS的DURATION的和
+func (l *AssetList) Duration() float64 {
+	var d float64
+	for i := 0; i < len(l.ASSETS); i++ {
+		d += l.ASSETS[i].DURATION
+	}
+	return d
+}
+
+// 输出JSON
+func (l *AssetList) WriteTo(w io.Writer) (int64, error) {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
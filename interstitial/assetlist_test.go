@@ -0,0 +1,56 @@
+package interstitial
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `{"ASSETS":[{"URI":"a.m3u8","DURATION":15},{"URI":"b.m3u8","DURATION":30.5}],` +
+		`"SKIP-CONTROL":{"OFFSET":5,"DURATION":10,"LABEL-ID":"skip"}}`
+	l, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.ASSETS) != 2 || l.ASSETS[1].URI != "b.m3u8" || l.Duration() != 45.5 {
+		t.Fatalf("got %+v", l)
+	}
+	c := l.SKIP_CONTROL
+	if c == nil || c.OFFSET != 5 || c.DURATION == nil || *c.DURATION != 10 || c.LABEL_ID != "skip" {
+		t.Fatalf("got %+v", c)
+	}
+	var b strings.Builder
+	n, err := l.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != src || n != int64(len(src)) {
+		t.Fatalf("got %d %s, want %s", n, b.String(), src)
+	}
+	// 没有SKIP-CONTROL不输出
+	b.Reset()
+	if _, err := NewAssetList(Asset{URI: "a.m3u8", DURATION: 15}).WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if s := `{"ASSETS":[{"URI":"a.m3u8","DURATION":15}]}`; b.String() != s {
+		t.Fatalf("got %s, want %s", b.String(), s)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	if _, err := Parse(strings.NewReader(`{"ASSETS":[]}`)); !errors.Is(err, ErrEmptyAssets) {
+		t.Fatalf("got %v, want %v", err, ErrEmptyAssets)
+	}
+	for _, src := range []string{
+		`{"ASSETS":`,
+		`{"ASSETS":[{"DURATION":15}]}`,
+		`{"ASSETS":[{"URI":"a.m3u8","DURATION":-1}]}`,
+		`{"ASSETS":[{"URI":"a.m3u8","DURATION":15}],"SKIP-CONTROL":{"OFFSET":-1}}`,
+		`{"ASSETS":[{"URI":"a.m3u8","DURATION":15}],"SKIP-CONTROL":{"OFFSET":0,"DURATION":-1}}`,
+	} {
+		if _, err := Parse(strings.NewReader(src)); err == nil {
+			t.Fatalf("%s: want error", src)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package m3u8
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInterstitial(t *testing.T) {
+	src := "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00.000Z\n" +
+		"#EXT-X-DATERANGE:ID=\"ad\",CLASS=\"com.apple.hls.interstitial\",START-DATE=\"2020-01-01T00:00:00.000Z\",CUE=\"PRE,ONCE\"," +
+		"X-COM-EXAMPLE=\"a\",X-ASSET-URI=\"ad.m3u8\",X-RESUME-OFFSET=0,X-PLAYOUT-LIMIT=15.5,X-SNAP=\"OUT,IN\",X-RESTRICT=\"SKIP\"\n" +
+		"#EXTINF:10,\n" +
+		"a.ts\n"
+	p, err := ParseMediaPlayList(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &p.MediaSegment[0].EXT_X_DATERANGE[0]
+	if c := r.Cues(); len(c) != 2 || c[0] != CuePre || c[1] != CueOnce {
+		t.Fatalf("got %v", c)
+	}
+	i, err := r.Interstitial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.X_ASSET_URI != "ad.m3u8" || i.X_ASSET_LIST != "" ||
+		i.X_RESUME_OFFSET == nil || *i.X_RESUME_OFFSET != 0 ||
+		i.X_PLAYOUT_LIMIT == nil || *i.X_PLAYOUT_LIMIT != 15.5 ||
+		strings.Join(i.X_SNAP, ",") != "OUT,IN" || strings.Join(i.X_RESTRICT, ",") != "SKIP" {
+		t.Fatalf("got %+v", i)
+	}
+	if s := encodeString(t, p); s != src {
+		t.Fatalf("got\n%s\nwant\n%s", s, src)
+	}
+	// 换成X-ASSET-LIST，删除空的属性，其他的X-属性不变
+	i.X_ASSET_URI = ""
+	i.X_ASSET_LIST = "ad.json"
+	i.X_RESUME_OFFSET = nil
+	i.X_SNAP = nil
+	if err := r.SetInterstitial(i); err != nil {
+		t.Fatal(err)
+	}
+	want := ClientAttributes{
+		{"X-COM-EXAMPLE", ClientString, "a"},
+		{"X-PLAYOUT-LIMIT", ClientFloat, "15.5"},
+		{"X-RESTRICT", ClientString, "SKIP"},
+		{"X-ASSET-LIST", ClientString, "ad.json"},
+	}
+	if a := r.X_CLIENT_ATTRIBUTE; len(a) != len(want) {
+		t.Fatalf("got %v, want %v", a, want)
+	}
+	for n := range want {
+		if r.X_CLIENT_ATTRIBUTE[n] != want[n] {
+			t.Fatalf("got %v, want %v", r.X_CLIENT_ATTRIBUTE, want)
+		}
+	}
+	// 不是Interstitial
+	r = &EXT_X_DATERANGE{ID: "a", CLASS: "x"}
+	if i, err := r.Interstitial(); i != nil || err != nil {
+		t.Fatalf("got %v %v", i, err)
+	}
+}
+
+func TestSetInterstitialError(t *testing.T) {
+	for _, i := range []Interstitial{
+		// X-ASSET-URI和X-ASSET-LIST只能有一个
+		{},
+		{X_ASSET_URI: "ad.m3u8", X_ASSET_LIST: "ad.json"},
+		{X_ASSET_URI: "ad.m3u8", X_RESUME_OFFSET: float(-1)},
+		{X_ASSET_URI: "ad.m3u8", X_PLAYOUT_LIMIT: float(-1)},
+	} {
+		i := i
+		var r EXT_X_DATERANGE
+		if err := r.SetInterstitial(&i); err == nil {
+			t.Fatalf("%+v: want error", i)
+		}
+		if r.CLASS != "" || len(r.X_CLIENT_ATTRIBUTE) != 0 {
+			t.Fatalf("%+v: got %+v", i, r)
+		}
+	}
+	// <decimal-floating-point>不能是负数
+	src := "#EXTM3U\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00.000Z\n" +
+		"#EXT-X-DATERANGE:ID=\"ad\",CLASS=\"com.apple.hls.interstitial\",START-DATE=\"2020-01-01T00:00:00.000Z\"," +
+		"X-ASSET-URI=\"ad.m3u8\",X-RESUME-OFFSET=-1\n" +
+		"#EXTINF:10,\n" +
+		"a.ts\n"
+	if _, err := ParseMediaPlayList(strings.NewReader(src)); !errors.Is(err, ErrMalformedAttribute) {
+		t.Fatalf("got %v, want %v", err, ErrMalformedAttribute)
+	}
+}
@@ -0,0 +1,50 @@
+package m3u8
+
+// 没有KEYFORMAT的#EXT-X-KEY使用的格式，RFC8216 4.3.2.4
+const DefaultKeyFormat = "identity"
+
+// 返回key的KEYFORMAT，没有返回DefaultKeyFormat
+func (k *EXT_X_KEY) KeyFormat() string {
+	if k.KEYFORMAT == "" {
+		return DefaultKeyFormat
+	}
+	return k.KEYFORMAT
+}
+
+// 返回keys应用key以后生效的#EXT-X-KEY，keys不会被修改。
+// 一个片段可以同时有多个KEYFORMAT的key（比如FairPlay和Widevine），
+// key替换KEYFORMAT一样的那个，没有就添加到后面，METHOD=NONE清除所有的key返回nil
+func UpdateKeys(keys []*EXT_X_KEY, key *EXT_X_KEY) []*EXT_X_KEY {
+	if key.METHOD == "NONE" {
+		return nil
+	}
+	// 前面的片段使用了keys，不能修改
+	k := make([]*EXT_X_KEY, len(keys), len(keys)+1)
+	copy(k, keys)
+	i := indexKeyFormat(k, key.KeyFormat())
+	if i < 0 {
+		return append(k, key)
+	}
+	k[i] = key
+	return k
+}
+
+// 返回keys中KEYFORMAT是format的下标，没有返回-1
+func indexKeyFormat(keys []*EXT_X_KEY, format string) int {
+	for i := 0; i < len(keys); i++ {
+		if keys[i].KeyFormat() == format {
+			return i
+		}
+	}
+	return -1
+}
+
+// 返回keys中是否有key这个指针
+func containsKey(keys []*EXT_X_KEY, key *EXT_X_KEY) bool {
+	for i := 0; i < len(keys); i++ {
+		if keys[i] == key {
+			return true
+		}
+	}
+	return false
+}
@@ -4,7 +4,7 @@ package m3u8
 
 import (
 	"bytes"
-	"fmt"
+	"time"
 )
 
 const (
@@ -20,6 +20,8 @@ const (
 	TagEXT_X_MAP               = "#EXT-X-MAP"
 	TagEXT_X_PROGRAM_DATE_TIME = "#EXT-X-PROGRAM-DATE-TIME"
 	TagEXT_X_DATERANGE         = "#EXT-X-DATERANGE"
+	TagEXT_X_GAP               = "#EXT-X-GAP"
+	TagEXT_X_BITRATE           = "#EXT-X-BITRATE"
 	// media playlist tags
 	TagEXT_X_TARGETDURATION         = "#EXT-X-TARGETDURATION"
 	TagEXT_X_MEDIA_SEQUENCE         = "#EXT-X-MEDIA-SEQUENCE"
@@ -35,9 +37,22 @@ const (
 	TagEXT_X_SESSION_KEY          = "#EXT-X-SESSION-KEY"
 	TagEXT_X_INDEPENDENT_SEGMENTS = "#EXT-X-INDEPENDENT-SEGMENTS"
 	TagEXT_X_START                = "#EXT-X-START"
+	TagEXT_X_DEFINE               = "#EXT-X-DEFINE"
+	TagEXT_X_CONTENT_STEERING     = "#EXT-X-CONTENT-STEERING"
+	// low-latency tags，draft-pantos-hls-rfc8216bis
+	TagEXT_X_PART             = "#EXT-X-PART"
+	TagEXT_X_PART_INF         = "#EXT-X-PART-INF"
+	TagEXT_X_SERVER_CONTROL   = "#EXT-X-SERVER-CONTROL"
+	TagEXT_X_PRELOAD_HINT     = "#EXT-X-PRELOAD-HINT"
+	TagEXT_X_RENDITION_REPORT = "#EXT-X-RENDITION-REPORT"
+	TagEXT_X_SKIP             = "#EXT-X-SKIP"
 )
 
 var (
+	// basic tags
+	tagEXTM3U        = []byte(TagEXTM3U)
+	tagEXT_X_VERSION = []byte(TagEXT_X_VERSION)
+	// media segment tags
 	tagEXTINF                  = []byte(TagEXTINF)
 	tagEXT_X_BYTERANGE         = []byte(TagEXT_X_BYTERANGE)
 	tagEXT_X_DISCONTINUITY     = []byte(TagEXT_X_DISCONTINUITY)
@@ -45,6 +60,8 @@ var (
 	tagEXT_X_MAP               = []byte(TagEXT_X_MAP)
 	tagEXT_X_PROGRAM_DATE_TIME = []byte(TagEXT_X_PROGRAM_DATE_TIME)
 	tagEXT_X_DATERANGE         = []byte(TagEXT_X_DATERANGE)
+	tagEXT_X_GAP               = []byte(TagEXT_X_GAP)
+	tagEXT_X_BITRATE           = []byte(TagEXT_X_BITRATE)
 	// media playlist tags
 	tagEXT_X_TARGETDURATION         = []byte(TagEXT_X_TARGETDURATION)
 	tagEXT_X_MEDIA_SEQUENCE         = []byte(TagEXT_X_MEDIA_SEQUENCE)
@@ -60,125 +77,251 @@ var (
 	tagEXT_X_SESSION_KEY          = []byte(TagEXT_X_SESSION_KEY)
 	tagEXT_X_INDEPENDENT_SEGMENTS = []byte(TagEXT_X_INDEPENDENT_SEGMENTS)
 	tagEXT_X_START                = []byte(TagEXT_X_START)
+	tagEXT_X_DEFINE               = []byte(TagEXT_X_DEFINE)
+	tagEXT_X_CONTENT_STEERING     = []byte(TagEXT_X_CONTENT_STEERING)
+	// low-latency tags
+	tagEXT_X_PART             = []byte(TagEXT_X_PART)
+	tagEXT_X_PART_INF         = []byte(TagEXT_X_PART_INF)
+	tagEXT_X_SERVER_CONTROL   = []byte(TagEXT_X_SERVER_CONTROL)
+	tagEXT_X_PRELOAD_HINT     = []byte(TagEXT_X_PRELOAD_HINT)
+	tagEXT_X_RENDITION_REPORT = []byte(TagEXT_X_RENDITION_REPORT)
+	tagEXT_X_SKIP             = []byte(TagEXT_X_SKIP)
 	// empty slice
 	emptySlice = make([]byte, 0)
 )
 
 type EXTINF struct {
-	DURATION string
+	DURATION float64
 	TITLE    string
 }
 
 type EXT_X_BYTERANGE struct {
-	N string
-	O string
+	N int64
+	O *int64 // nil表示没有@<o>
 }
 
 type EXT_X_KEY struct {
-	METHOD            string
-	URI               string
-	IV                string
-	KEYFORMAT         string
-	KEYFORMATVERSIONS string
+	METHOD            string `m3u8:"METHOD,required,enum=NONE|AES-128|SAMPLE-AES|SAMPLE-AES-CTR"`
+	URI               string `m3u8:"URI,quoted"`
+	IV                []byte `m3u8:"IV"`
+	KEYFORMAT         string `m3u8:"KEYFORMAT,quoted"`
+	KEYFORMATVERSIONS string `m3u8:"KEYFORMATVERSIONS,quoted"`
+	Line              int    // 解析时tag的行号，0表示不是解析出来的
 }
 
 type EXT_X_MAP struct {
-	URI       string
-	BYTERANGE string
+	URI       string           `m3u8:"URI,quoted,required"`
+	BYTERANGE *EXT_X_BYTERANGE `m3u8:"BYTERANGE,quoted"`
+	Line      int              // 解析时tag的行号，0表示不是解析出来的
 }
 
 type EXT_X_DATERANGE struct {
-	ID                 string
-	CLASS              string
-	START_DATE         string
-	END_DATE           string
-	DURATION           string
-	PLANNED_DURATION   string
-	X_CLIENT_ATTRIBUTE map[string]string
-	SCTE35_CMD         string
-	SCTE35_OUT         string
-	SCTE35_IN          string
-	END_ON_NEXT        string
+	ID                 string           `m3u8:"ID,quoted,required"`
+	CLASS              string           `m3u8:"CLASS,quoted"`
+	START_DATE         time.Time        `m3u8:"START-DATE,required"`
+	CUE                string           `m3u8:"CUE,quoted"` // PRE，POST，ONCE，用','分开
+	END_DATE           time.Time        `m3u8:"END-DATE"`
+	DURATION           *float64         `m3u8:"DURATION"`
+	PLANNED_DURATION   *float64         `m3u8:"PLANNED-DURATION"`
+	X_CLIENT_ATTRIBUTE ClientAttributes `m3u8:"X-,prefix"`
+	SCTE35_CMD         []byte           `m3u8:"SCTE35-CMD"`
+	SCTE35_OUT         []byte           `m3u8:"SCTE35-OUT"`
+	SCTE35_IN          []byte           `m3u8:"SCTE35-IN"`
+	END_ON_NEXT        bool             `m3u8:"END-ON-NEXT,enum=YES"`
+	Line               int              // 解析时tag的行号，0表示不是解析出来的
 }
 
 type EXT_X_MEDIA struct {
-	TYPE            string
-	URI             string
-	GROUP_ID        string
-	LANGUAGE        string
-	ASSOC_LANGUAGE  string
-	NAME            string
-	DEFAULT         string
-	AUTOSELECT      string
-	FORCED          string
-	INSTREAM_ID     string
-	CHARACTERISTICS string
-	CHANNELS        string
+	TYPE                string `m3u8:"TYPE,required,enum=AUDIO|VIDEO|SUBTITLES|CLOSED-CAPTIONS"`
+	URI                 string `m3u8:"URI,quoted"`
+	GROUP_ID            string `m3u8:"GROUP-ID,quoted,required"`
+	LANGUAGE            string `m3u8:"LANGUAGE,quoted"`
+	ASSOC_LANGUAGE      string `m3u8:"ASSOC-LANGUAGE,quoted"`
+	NAME                string `m3u8:"NAME,quoted,required"`
+	DEFAULT             bool   `m3u8:"DEFAULT"`
+	AUTOSELECT          bool   `m3u8:"AUTOSELECT"`
+	FORCED              bool   `m3u8:"FORCED"`
+	INSTREAM_ID         string `m3u8:"INSTREAM-ID,quoted"`
+	CHARACTERISTICS     string `m3u8:"CHARACTERISTICS,quoted"`
+	CHANNELS            string `m3u8:"CHANNELS,quoted"`
+	STABLE_RENDITION_ID string `m3u8:"STABLE-RENDITION-ID,quoted"`
+	BIT_DEPTH           int64  `m3u8:"BIT-DEPTH"`
+	SAMPLE_RATE         int64  `m3u8:"SAMPLE-RATE"`
+	Line                int    // 解析时tag的行号，0表示不是解析出来的
 }
 
 type EXT_X_STREAM_INF struct {
-	BANDWIDTH         string
-	AVERAGE_BANDWIDTH string
-	CODECS            string
-	RESOLUTION        string
-	FRAME_RATE        string
-	HDCP_LEVEL        string
-	AUDIO             string
-	VIDEO             string
-	SUBTITLES         string
-	CLOSED_CAPTIONS   string
-	URI               string
+	BANDWIDTH           int64      `m3u8:"BANDWIDTH,required"`
+	AVERAGE_BANDWIDTH   int64      `m3u8:"AVERAGE-BANDWIDTH"`
+	CODECS              string     `m3u8:"CODECS,quoted"`
+	RESOLUTION          Resolution `m3u8:"RESOLUTION"`
+	FRAME_RATE          float64    `m3u8:"FRAME-RATE,prec=3"`
+	HDCP_LEVEL          string     `m3u8:"HDCP-LEVEL,enum=TYPE-0|TYPE-1|NONE"`
+	AUDIO               string     `m3u8:"AUDIO,quoted"`
+	VIDEO               string     `m3u8:"VIDEO,quoted"`
+	SUBTITLES           string     `m3u8:"SUBTITLES,quoted"`
+	CLOSED_CAPTIONS     string     `m3u8:"CLOSED-CAPTIONS,none"`
+	SCORE               float64    `m3u8:"SCORE"`
+	SUPPLEMENTAL_CODECS string     `m3u8:"SUPPLEMENTAL-CODECS,quoted"`
+	VIDEO_RANGE         string     `m3u8:"VIDEO-RANGE,enum=SDR|HLG|PQ"`
+	REQ_VIDEO_LAYOUT    string     `m3u8:"REQ-VIDEO-LAYOUT,quoted"`
+	STABLE_VARIANT_ID   string     `m3u8:"STABLE-VARIANT-ID,quoted"`
+	ALLOWED_CPC         string     `m3u8:"ALLOWED-CPC,quoted"`
+	PATHWAY_ID          string     `m3u8:"PATHWAY-ID,quoted"`
+	URI                 string
+	Line                int // 解析时tag的行号，0表示不是解析出来的
 }
 
 type EXT_X_I_FRAME_STREAM_INF struct {
-	BANDWIDTH         string
-	AVERAGE_BANDWIDTH string
-	CODECS            string
-	RESOLUTION        string
-	HDCP_LEVEL        string
-	VIDEO             string
-	URI               string
+	BANDWIDTH           int64      `m3u8:"BANDWIDTH,required"`
+	AVERAGE_BANDWIDTH   int64      `m3u8:"AVERAGE-BANDWIDTH"`
+	CODECS              string     `m3u8:"CODECS,quoted"`
+	RESOLUTION          Resolution `m3u8:"RESOLUTION"`
+	HDCP_LEVEL          string     `m3u8:"HDCP-LEVEL,enum=TYPE-0|TYPE-1|NONE"`
+	VIDEO               string     `m3u8:"VIDEO,quoted"`
+	SCORE               float64    `m3u8:"SCORE"`
+	SUPPLEMENTAL_CODECS string     `m3u8:"SUPPLEMENTAL-CODECS,quoted"`
+	VIDEO_RANGE         string     `m3u8:"VIDEO-RANGE,enum=SDR|HLG|PQ"`
+	REQ_VIDEO_LAYOUT    string     `m3u8:"REQ-VIDEO-LAYOUT,quoted"`
+	STABLE_VARIANT_ID   string     `m3u8:"STABLE-VARIANT-ID,quoted"`
+	ALLOWED_CPC         string     `m3u8:"ALLOWED-CPC,quoted"`
+	PATHWAY_ID          string     `m3u8:"PATHWAY-ID,quoted"`
+	URI                 string     `m3u8:"URI,quoted,required"`
+	Line                int        // 解析时tag的行号，0表示不是解析出来的
 }
 
 type EXT_X_SESSION_DATA struct {
-	DATA_ID  string
-	VALUE    string
-	URI      string
-	LANGUAGE string
+	DATA_ID  string `m3u8:"DATA-ID,quoted,required"`
+	VALUE    string `m3u8:"VALUE,quoted"`
+	URI      string `m3u8:"URI,quoted"`
+	LANGUAGE string `m3u8:"LANGUAGE,quoted"`
+	Line     int    // 解析时tag的行号，0表示不是解析出来的
 }
 
 type EXT_X_START struct {
-	TIME_OFFSET string
-	PRECISE     string
+	TIME_OFFSET float64 `m3u8:"TIME-OFFSET,required,signed"`
+	PRECISE     bool    `m3u8:"PRECISE"`
+	Line        int     // 解析时tag的行号，0表示不是解析出来的
+}
+
+// NAME，IMPORT和QUERYPARAM只能有一个
+type EXT_X_DEFINE struct {
+	NAME       string `m3u8:"NAME,quoted"`
+	VALUE      string `m3u8:"VALUE,quoted"`
+	IMPORT     string `m3u8:"IMPORT,quoted"`
+	QUERYPARAM string `m3u8:"QUERYPARAM,quoted"`
+	Line       int    // 解析时tag的行号，0表示不是解析出来的
+}
+
+// SERVER-URI是steering manifest的地址，PATHWAY-ID是开始使用的路径
+type EXT_X_CONTENT_STEERING struct {
+	SERVER_URI string `m3u8:"SERVER-URI,quoted,required"`
+	PATHWAY_ID string `m3u8:"PATHWAY-ID,quoted"`
+	Line       int    // 解析时tag的行号，0表示不是解析出来的
+}
+
+type EXT_X_PART struct {
+	URI         string           `m3u8:"URI,quoted,required"`
+	DURATION    float64          `m3u8:"DURATION,required"`
+	INDEPENDENT bool             `m3u8:"INDEPENDENT,enum=YES"`
+	BYTERANGE   *EXT_X_BYTERANGE `m3u8:"BYTERANGE,quoted"`
+	GAP         bool             `m3u8:"GAP,enum=YES"`
+	Line        int              // 解析时tag的行号，0表示不是解析出来的
+}
+
+type EXT_X_PART_INF struct {
+	PART_TARGET float64 `m3u8:"PART-TARGET,required"`
+	Line        int     // 解析时tag的行号，0表示不是解析出来的
+}
+
+type EXT_X_SERVER_CONTROL struct {
+	CAN_SKIP_UNTIL      float64 `m3u8:"CAN-SKIP-UNTIL"`
+	CAN_SKIP_DATERANGES bool    `m3u8:"CAN-SKIP-DATERANGES,enum=YES"`
+	HOLD_BACK           float64 `m3u8:"HOLD-BACK"`
+	PART_HOLD_BACK      float64 `m3u8:"PART-HOLD-BACK"`
+	CAN_BLOCK_RELOAD    bool    `m3u8:"CAN-BLOCK-RELOAD,enum=YES"`
+	Line                int     // 解析时tag的行号，0表示不是解析出来的
+}
+
+type EXT_X_PRELOAD_HINT struct {
+	TYPE             string `m3u8:"TYPE,required,enum=PART|MAP"`
+	URI              string `m3u8:"URI,quoted,required"`
+	BYTERANGE_START  int64  `m3u8:"BYTERANGE-START"`
+	BYTERANGE_LENGTH int64  `m3u8:"BYTERANGE-LENGTH"`
+	Line             int    // 解析时tag的行号，0表示不是解析出来的
+}
+
+type EXT_X_RENDITION_REPORT struct {
+	URI       string `m3u8:"URI,quoted"`
+	LAST_MSN  *int64 `m3u8:"LAST-MSN"`
+	LAST_PART *int64 `m3u8:"LAST-PART"`
+	Line      int    // 解析时tag的行号，0表示不是解析出来的
+}
+
+type EXT_X_SKIP struct {
+	SKIPPED_SEGMENTS int64 `m3u8:"SKIPPED-SEGMENTS,required"`
+	// 用'\t'分开的ID列表
+	RECENTLY_REMOVED_DATERANGES string `m3u8:"RECENTLY-REMOVED-DATERANGES,quoted"`
+	Line                        int    // 解析时tag的行号，0表示不是解析出来的
+}
+
+// 不认识的tag或者注释，原样输出。
+// Index是它在所在部分中的行号，从0开始，包括所有不是空的行，
+// 输出时放回这个位置，超过这部分的行数的放在这部分的最后
+type UnknownLine struct {
+	Index int
+	Line  string
 }
 
 type MediaSegment struct {
 	EXTINF                  EXTINF
 	EXT_X_BYTERANGE         *EXT_X_BYTERANGE
 	EXT_X_DISCONTINUITY     bool
-	EXT_X_KEY               *EXT_X_KEY
+	EXT_X_KEY               []*EXT_X_KEY // 生效的#EXT-X-KEY，每个KEYFORMAT一个，nil表示没有加密
 	EXT_X_MAP               *EXT_X_MAP
-	EXT_X_PROGRAM_DATE_TIME string
-	EXT_X_DATERANGE         *EXT_X_DATERANGE
+	EXT_X_PROGRAM_DATE_TIME time.Time
+	EXT_X_DATERANGE         []EXT_X_DATERANGE
+	EXT_X_GAP               bool
+	EXT_X_BITRATE           int64        // kbps，解析时继承前面的#EXT-X-BITRATE，0表示没有，有EXT_X_BYTERANGE时不使用
+	EXT_X_PART              []EXT_X_PART // 片段的部分，在URI前面
+	URI                     string
+	Tags                    []CustomTag   // 自定义的tag，片段从前一个片段的URI后面开始，到URI结束
+	Unknown                 []UnknownLine // 不认识的tag和注释，片段从前一个片段的URI后面开始，到URI结束
+	Line                    int           // 解析时URI的行号，0表示不是解析出来的
 }
 
 type MediaPlayList struct {
-	writer                       Writer
-	EXT_X_VERSION                string
-	EXT_X_TARGETDURATION         string
-	EXT_X_MEDIA_SEQUENCE         string
-	EXT_X_DISCONTINUITY_SEQUENCE string
+	variables                    map[string]string // 解析时的变量
+	EXT_X_VERSION                int64
+	EXT_X_DEFINE                 []EXT_X_DEFINE
+	EXT_X_TARGETDURATION         int64
+	EXT_X_MEDIA_SEQUENCE         int64
+	EXT_X_DISCONTINUITY_SEQUENCE int64
 	EXT_X_PLAYLIST_TYPE          string
 	EXT_X_I_FRAMES_ONLY          bool
 	EXT_X_INDEPENDENT_SEGMENTS   bool
 	EXT_X_START                  *EXT_X_START
+	EXT_X_SERVER_CONTROL         *EXT_X_SERVER_CONTROL
+	EXT_X_PART_INF               *EXT_X_PART_INF
+	Tags                         []CustomTag   // 自定义的tag，这部分从#EXTM3U后面开始，到第一个片段前面
+	Unknown                      []UnknownLine // 不认识的tag和注释，这部分从#EXTM3U后面开始，到第一个片段前面
+	EXT_X_SKIP                   *EXT_X_SKIP
 	MediaSegment                 []MediaSegment
+	TrailingTags                 []CustomTag          // 最后一个片段的URI后面自定义的tag
+	TrailingUnknown              []UnknownLine        // 最后一个片段的URI后面不认识的tag和注释
+	EXT_X_PART                   []EXT_X_PART         // 最后一个片段后面，还没有完成的片段的部分
+	EXT_X_PRELOAD_HINT           []EXT_X_PRELOAD_HINT // 一个PART，一个MAP
 	EXT_X_ENDLIST                bool
+	EXT_X_RENDITION_REPORT       []EXT_X_RENDITION_REPORT
+	// 解析时只有一个值的tag的行号，比如Lines[TagEXT_X_TARGETDURATION]，
+	// 有Line字段的tag不在这里
+	Lines map[string]int
 }
 
 type MasterPlayList struct {
-	writer                     Writer
-	EXT_X_VERSION              string
+	variables                  map[string]string // 解析时的变量
+	EXT_X_VERSION              int64
+	EXT_X_DEFINE               []EXT_X_DEFINE
 	EXT_X_MEDIA                []EXT_X_MEDIA
 	EXT_X_STREAM_INF           []EXT_X_STREAM_INF
 	EXT_X_I_FRAME_STREAM_INF   []EXT_X_I_FRAME_STREAM_INF
@@ -186,6 +329,12 @@ type MasterPlayList struct {
 	EXT_X_SESSION_KEY          *EXT_X_KEY
 	EXT_X_INDEPENDENT_SEGMENTS bool
 	EXT_X_START                *EXT_X_START
+	EXT_X_CONTENT_STEERING     *EXT_X_CONTENT_STEERING
+	Tags                       []CustomTag   // 自定义的tag，这部分从#EXTM3U后面开始
+	Unknown                    []UnknownLine // 不认识的tag和注释，这部分从#EXTM3U后面开始
+	// 解析时只有一个值的tag的行号，比如Lines[TagEXT_X_VERSION]，
+	// 有Line字段的tag不在这里
+	Lines map[string]int
 }
 
 func ParseLine(line []byte) (tag, value []byte) {
@@ -196,62 +345,126 @@ func ParseLine(line []byte) (tag, value []byte) {
 	return line[:i], line[i+1:]
 }
 
+// 解析<attribute-list>，返回name:value，value没有引号
 func ParseAttribute(line []byte) (map[string]string, error) {
-	m := make(map[string]string)
+	l, err := ParseAttributeList(line)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(l))
+	for i := 0; i < len(l); i++ {
+		m[l[i].Name] = l[i].Value
+	}
+	return m, nil
+}
+
+// 解析<attribute-list>，保持属性的顺序，重复的属性返回错误，RFC8216 4.2，
+// 错误是*ParseError，Column是在line中的位置
+func ParseAttributeList(line []byte) (AttributeList, error) {
+	return parseAttributeList(line, nil)
+}
+
+// 解析<attribute-list>，warn不是nil时，尽量修复格式错误，每个修复调用一次warn
+func parseAttributeList(line []byte, warn warnFunc) (AttributeList, error) {
+	var l AttributeList
 	i := 0
+	n := len(line)
 	for {
+		// 属性之间的空白
+		if warn != nil && len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			warn(n-len(line)+1, "whitespace before attribute")
+			line = bytes.TrimLeft(line, " \t")
+		}
+		// 当前属性的列号
+		column := n - len(line) + 1
 		// name=value
 		i = bytes.IndexByte(line, '=')
 		if i < 0 {
-			return nil, fmt.Errorf("incomplete attribute '%s', can't find '='", string(line))
+			return nil, attributeError(column, "incomplete attribute '%s', can't find '='", line)
 		}
 		// name
 		name := string(line[:i])
+		if name == "" {
+			return nil, attributeError(column, "incomplete attribute '%s', can't find <name>", line)
+		}
+		if !isAttributeName(name) {
+			if warn == nil {
+				return nil, attributeError(column, "invalid attribute name '%s'", name)
+			}
+			warn(column, "invalid attribute name '%s'", name)
+		}
+		// 重复的属性，宽松模式保留第一个
+		dup := l.Index(name) >= 0
+		if dup {
+			if warn == nil {
+				return nil, attributeError(column, "duplicate attribute '%s'", name)
+			}
+			warn(column, "duplicate attribute '%s', ignored", name)
+		}
 		// value...
 		line = line[i+1:]
-		if len(line) < 0 {
+		if len(line) <= 0 {
 			// name=
-			return nil, fmt.Errorf("incomplete attribute '%s', can't find <value>", string(line))
+			return nil, attributeError(column, "incomplete attribute '%s', can't find <value>", name)
 		}
 		if line[0] == '"' {
 			i = indexString(line)
 			if i < 0 {
 				// name="...
-				return m, fmt.Errorf("incomplete attribute '%s', can't find end '\"'", string(line))
-			} else {
-				// name="..."
-				m[name] = string(line[:i])
-				p := line[i+1:]
-				if len(p) > 0 {
-					if p[0] != ',' {
-						return m, fmt.Errorf("incomplete attribute, can't find ',' after '%s'", line[:i])
-					}
-					line = p[1:]
-				} else {
-					return m, nil
-				}
+				return nil, attributeError(column, "incomplete attribute '%s', can't find end '\"'", line)
+			}
+			// name="..."
+			if !dup {
+				l = append(l, Attribute{Name: name, Value: string(line[1:i]), Quoted: true, column: column})
 			}
+			p := line[i+1:]
+			if len(p) <= 0 {
+				return l, nil
+			}
+			if p[0] != ',' {
+				return nil, attributeError(n-len(p)+1, "incomplete attribute, can't find ',' after '%s'", line[:i+1])
+			}
+			line = p[1:]
 		} else {
 			i = bytes.IndexByte(line, ',')
 			if i < 0 {
-				m[name] = string(line)
-				return m, nil
-			} else {
-				m[name] = string(line[:i])
-				line = line[i+1:]
-				if len(line) <= 0 {
-					return m, nil
+				if !dup {
+					l = append(l, Attribute{Name: name, Value: string(line), column: column})
 				}
+				return l, nil
+			}
+			if !dup {
+				l = append(l, Attribute{Name: name, Value: string(line[:i]), column: column})
 			}
+			line = line[i+1:]
+		}
+		// name=value,
+		if len(line) <= 0 {
+			if warn == nil {
+				return nil, attributeError(n, "trailing ','")
+			}
+			warn(n, "trailing ','")
+			return l, nil
 		}
 	}
 }
 
-func indexString(s []byte) int {
-	for i := 1; i < len(s); i++ {
-		if s[i] == '"' && s[i-1] != '\\' {
-			return i
+// <attribute-name>只能是[A-Z]，[0-9]和'-'
+func isAttributeName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '-' {
+			return false
 		}
 	}
-	return -1
+	return true
+}
+
+// quoted-string没有转义字符，第一个'"'就是结束
+func indexString(s []byte) int {
+	i := bytes.IndexByte(s[1:], '"')
+	if i < 0 {
+		return -1
+	}
+	return i + 1
 }
@@ -0,0 +1,65 @@
+package m3u8
+
+// 没有PATHWAY-ID的variant属于默认的路径"."，draft-pantos-hls-rfc8216bis 4.4.6.2
+const DefaultPathwayID = "."
+
+// PATHWAY-ID只能是[a-z]，[A-Z]，[0-9]，'-'，'.'和'_'，不能是空
+func IsPathwayID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') &&
+			c != '-' && c != '.' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// 返回variant的路径，没有PATHWAY-ID返回DefaultPathwayID
+func (s *EXT_X_STREAM_INF) Pathway() string {
+	if s.PATHWAY_ID == "" {
+		return DefaultPathwayID
+	}
+	return s.PATHWAY_ID
+}
+
+// 返回variant的路径，没有PATHWAY-ID返回DefaultPathwayID
+func (s *EXT_X_I_FRAME_STREAM_INF) Pathway() string {
+	if s.PATHWAY_ID == "" {
+		return DefaultPathwayID
+	}
+	return s.PATHWAY_ID
+}
+
+// 返回所有variant的路径，按出现的顺序，不重复
+func (p *MasterPlayList) Pathways() []string {
+	var ids []string
+	has := make(map[string]bool)
+	add := func(id string) {
+		if !has[id] {
+			has[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for i := 0; i < len(p.EXT_X_STREAM_INF); i++ {
+		add(p.EXT_X_STREAM_INF[i].Pathway())
+	}
+	for i := 0; i < len(p.EXT_X_I_FRAME_STREAM_INF); i++ {
+		add(p.EXT_X_I_FRAME_STREAM_INF[i].Pathway())
+	}
+	return ids
+}
+
+// 返回路径是id的#EXT-X-STREAM-INF
+func (p *MasterPlayList) PathwayVariants(id string) []*EXT_X_STREAM_INF {
+	var s []*EXT_X_STREAM_INF
+	for i := 0; i < len(p.EXT_X_STREAM_INF); i++ {
+		if p.EXT_X_STREAM_INF[i].Pathway() == id {
+			s = append(s, &p.EXT_X_STREAM_INF[i])
+		}
+	}
+	return s
+}
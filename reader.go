@@ -11,6 +11,7 @@ type Reader struct {
 	pIdx   int       // data解析的索引
 	dIdx   int       // data有效数据的索引
 	dLen   int       // data的有效数据大小
+	line   int       // 已经读取的行数
 	reader io.Reader // 数据源
 }
 
@@ -28,10 +29,26 @@ func NewReader(r io.Reader, b []byte) *Reader {
 // 重新设置数据源r
 func (r *Reader) SetReader(reader io.Reader) {
 	r.reader = reader
+	r.line = 0
+}
+
+// 返回最后一次ReadLine()读取的行号，从1开始
+func (r *Reader) Line() int {
+	return r.line
 }
 
 // 读取一行数据，返回的数据，外部需要拷贝
 func (r *Reader) ReadLine() ([]byte, error) {
+	p, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	r.line++
+	return p, nil
+}
+
+// 读取一行数据
+func (r *Reader) readLine() ([]byte, error) {
 	// 从缓存中读取数据
 	p := r.readData()
 	if p != nil {
@@ -43,6 +60,31 @@ func (r *Reader) ReadLine() ([]byte, error) {
 	for {
 		// 从reader读取数据
 		n, err = r.reader.Read(r.buff)
+		// 先处理读到的数据，Read()可能同时返回数据和io.EOF
+		if n > 0 {
+			// data没有数据，先解析buff，减小拷贝
+			if r.dLen == 0 {
+				// buff中是否有完整的一行
+				i := bytes.IndexByte(r.buff[:n], '\n')
+				if i >= 0 {
+					p = r.checkEnter(r.buff[:i])
+					// 添加buff[i+1:n]到data
+					r.appendData(r.buff[i+1 : n])
+					// 返回
+					return p, nil
+				}
+				// 添加buff[:n]到data
+				r.appendData(r.buff[:n])
+			} else {
+				// data中有数据，添加buff[:n]到data
+				r.appendData(r.buff[:n])
+				// 从data中读取数据
+				p = r.readData()
+				if p != nil {
+					return p, nil
+				}
+			}
+		}
 		if err != nil {
 			// 没有数据了
 			if err == io.EOF {
@@ -52,40 +94,11 @@ func (r *Reader) ReadLine() ([]byte, error) {
 				r.pIdx = 0
 				r.dLen = 0
 				if len(p) > 0 {
-					n = len(p) - 1
-					if p[n] == '\n' {
-						p = p[:n]
-					}
 					return r.checkEnter(p), nil
 				}
 			}
 			return nil, err
 		}
-		// data没有数据，先解析buff，减小拷贝
-		if r.dLen == 0 {
-			// buff中是否有完整的一行
-			i := bytes.IndexByte(r.buff[:n], '\n')
-			if i >= 0 {
-				p = r.checkEnter(r.buff[:i])
-				// 添加buff[m:n]到data
-				r.appendData(r.buff[i+1 : n])
-				// 返回
-				if len(p) > 0 {
-					return p, nil
-				}
-			}
-			// 添加buff[:n]到data
-			r.appendData(r.buff[:n])
-			// 继续读取
-			continue
-		}
-		// data中有数据，添加buff[:n]到data
-		r.appendData(r.buff[:n])
-		// 从data中读取数据
-		p := r.readData()
-		if p != nil {
-			return p, nil
-		}
 	}
 }
 
@@ -112,6 +125,13 @@ func (r *Reader) readData() []byte {
 
 // 添加数据到data缓存
 func (r *Reader) appendData(b []byte) {
+	// 移动没有读取的数据到data的开始，data的大小不会超过最长的一行
+	if r.dIdx > 0 {
+		copy(r.data, r.data[r.dIdx:r.dLen])
+		r.pIdx -= r.dIdx
+		r.dLen -= r.dIdx
+		r.dIdx = 0
+	}
 	i := copy(r.data[r.dLen:], b)
 	if i < len(b) {
 		r.data = append(r.data, b[i:]...)
@@ -0,0 +1,57 @@
+package m3u8
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReaderReadLine(t *testing.T) {
+	long := strings.Repeat("x", 1000)
+	src := "#EXTM3U\r\n" + long + "\n\n#EXTINF:10,\na.ts"
+	lines := []string{"#EXTM3U", long, "", "#EXTINF:10,", "a.ts"}
+	readers := map[string]func(io.Reader) io.Reader{
+		"full":     func(r io.Reader) io.Reader { return r },
+		"one byte": iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+		"data err": iotest.DataErrReader,
+	}
+	for name, wrap := range readers {
+		r := NewReader(wrap(strings.NewReader(src)), make([]byte, 16))
+		for i, want := range lines {
+			line, err := r.ReadLine()
+			if err != nil {
+				t.Fatalf("%s: line %d: %v", name, i+1, err)
+			}
+			if string(line) != want {
+				t.Fatalf("%s: line %d: got %q, want %q", name, i+1, line, want)
+			}
+			if r.Line() != i+1 {
+				t.Fatalf("%s: Line() got %d, want %d", name, r.Line(), i+1)
+			}
+		}
+		if _, err := r.ReadLine(); err != io.EOF {
+			t.Fatalf("%s: got %v, want io.EOF", name, err)
+		}
+	}
+}
+
+// 读过的数据要移到data的开始，data的大小不会超过最长的一行
+func TestReaderCompact(t *testing.T) {
+	line := strings.Repeat("y", 100)
+	src := strings.Repeat(line+"\n", 1000)
+	r := NewReader(iotest.HalfReader(strings.NewReader(src)), make([]byte, 64))
+	for i := 0; i < 1000; i++ {
+		p, err := r.ReadLine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(p) != line {
+			t.Fatalf("line %d: got %q", i+1, p)
+		}
+	}
+	if n := cap(r.data); n > 4*len(line) {
+		t.Fatalf("data grows to %d bytes", n)
+	}
+}
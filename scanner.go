@@ -0,0 +1,116 @@
+package m3u8
+
+import (
+	"io"
+	"net/url"
+)
+
+// Scanner解析媒体列表时的事件，返回的错误会结束解析
+type Handler interface {
+	// 第一个片段之前调用一次，p只有列表的tag，没有片段。
+	// 没有片段的列表在OnEnd之前调用
+	OnHeader(p *MediaPlayList) error
+	// 片段的#EXT-X-DATERANGE，在所属片段的OnSegment之前调用
+	OnDateRange(r *EXT_X_DATERANGE) error
+	// 一个片段，EXT_X_KEY，EXT_X_MAP和EXT_X_BITRATE是当前生效的
+	OnSegment(s *MediaSegment) error
+	// 解析完成，p没有片段，有最后一个片段后面的tag，
+	// 比如#EXT-X-ENDLIST，#EXT-X-PRELOAD-HINT，还有片段之间出现的列表的tag
+	OnEnd(p *MediaPlayList) error
+}
+
+// 流式解析媒体列表，不保存片段，每个片段解析完成后调用Handler，
+// 内存的使用和片段的数量无关，适合很大的列表
+type Scanner struct {
+	decoder *Decoder
+}
+
+// 创建Scanner，r是数据源，mode是解析模式
+func NewScanner(r io.Reader, mode ParseMode) *Scanner {
+	return &Scanner{decoder: NewDecoder(r, mode)}
+}
+
+// 设置解析自定义tag的注册表，默认是DefaultTagRegistry
+func (s *Scanner) SetTagRegistry(r *TagRegistry) {
+	s.decoder.SetTagRegistry(r)
+}
+
+// 设置不认识并且没有注册的#EXT-X-的tag是否返回ErrUnknownTag，默认不返回
+func (s *Scanner) SetUnknownTagError(enable bool) {
+	s.decoder.SetUnknownTagError(enable)
+}
+
+// 设置媒体列表所属的主列表，#EXT-X-DEFINE的IMPORT从它的Variables()中导入变量
+func (s *Scanner) SetParent(parent *MasterPlayList) {
+	s.decoder.SetParent(parent)
+}
+
+// 设置列表的URL，#EXT-X-DEFINE的QUERYPARAM从它的query中获取变量的值
+func (s *Scanner) SetURL(u *url.URL) {
+	s.decoder.SetURL(u)
+}
+
+// 宽松模式下修复的记录
+func (s *Scanner) Warnings() []Warning {
+	return s.decoder.Warnings()
+}
+
+// 解析媒体列表，按顺序调用h，每个Scanner只能调用一次
+func (s *Scanner) Scan(h Handler) error {
+	d := s.decoder
+	d.kind = MediaPlayListType
+	d.handler = h
+	err := d.decode()
+	if err != nil {
+		return err
+	}
+	// 最后一个片段后面的#EXT-X-DATERANGE
+	if d.segment != nil {
+		err = d.handleDateRanges(d.segment)
+		if err != nil {
+			return err
+		}
+	}
+	err = d.endMedia()
+	if err != nil {
+		return err
+	}
+	err = d.handleHeader()
+	if err != nil {
+		return err
+	}
+	return h.OnEnd(d.media)
+}
+
+// 第一次调用OnHeader
+func (d *Decoder) handleHeader() error {
+	if d.header {
+		return nil
+	}
+	d.header = true
+	return d.handler.OnHeader(d.media)
+}
+
+// 调用OnDateRange
+func (d *Decoder) handleDateRanges(s *MediaSegment) error {
+	for i := 0; i < len(s.EXT_X_DATERANGE); i++ {
+		err := d.handler.OnDateRange(&s.EXT_X_DATERANGE[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 一个片段解析完成
+func (d *Decoder) handleSegment(s *MediaSegment) error {
+	err := d.handleHeader()
+	if err != nil {
+		return err
+	}
+	err = d.handleDateRanges(s)
+	if err != nil {
+		return err
+	}
+	return d.handler.OnSegment(s)
+}
@@ -0,0 +1,140 @@
+package m3u8
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// 记录Scanner的事件
+type testHandler struct {
+	events []string
+	stop   string // 返回错误的事件
+}
+
+var errTestStop = errors.New("stop")
+
+func (h *testHandler) event(format string, args ...interface{}) error {
+	e := fmt.Sprintf(format, args...)
+	h.events = append(h.events, e)
+	if e == h.stop {
+		return errTestStop
+	}
+	return nil
+}
+
+func (h *testHandler) OnHeader(p *MediaPlayList) error {
+	return h.event("header %d %d", p.EXT_X_TARGETDURATION, len(p.MediaSegment))
+}
+
+func (h *testHandler) OnDateRange(r *EXT_X_DATERANGE) error {
+	return h.event("daterange %s", r.ID)
+}
+
+func (h *testHandler) OnSegment(s *MediaSegment) error {
+	key := ""
+	if len(s.EXT_X_KEY) > 0 {
+		key = s.EXT_X_KEY[0].URI
+	}
+	return h.event("segment %s %s", s.URI, key)
+}
+
+func (h *testHandler) OnEnd(p *MediaPlayList) error {
+	return h.event("end %v %d", p.EXT_X_ENDLIST, len(p.MediaSegment))
+}
+
+const testScannerPlayList = `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00.000Z
+#EXT-X-DATERANGE:ID="a",START-DATE="2020-01-01T00:00:00.000Z"
+#EXT-X-KEY:METHOD=AES-128,URI="k"
+#EXTINF:10,
+a.ts
+#EXTINF:10,
+b.ts
+#EXT-X-DATERANGE:ID="b",START-DATE="2020-01-01T00:00:20.000Z"
+#EXTINF:10,
+c.ts
+#EXT-X-DATERANGE:ID="c",START-DATE="2020-01-01T00:00:30.000Z"
+#EXT-X-ENDLIST
+`
+
+func TestScanner(t *testing.T) {
+	h := new(testHandler)
+	if err := NewScanner(strings.NewReader(testScannerPlayList), Strict).Scan(h); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"header 10 0",
+		"daterange a",
+		"segment a.ts k",
+		"segment b.ts k",
+		"daterange b",
+		"segment c.ts k",
+		"daterange c",
+		"end true 0",
+	}
+	if strings.Join(h.events, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got %q, want %q", h.events, want)
+	}
+}
+
+// 没有片段的列表在OnEnd之前调用OnHeader
+func TestScannerNoSegment(t *testing.T) {
+	h := new(testHandler)
+	if err := NewScanner(strings.NewReader("#EXTM3U\n#EXT-X-TARGETDURATION:10\n"), Strict).Scan(h); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(h.events, ",") != "header 10 0,end false 0" {
+		t.Fatalf("got %q", h.events)
+	}
+}
+
+// Handler返回的错误结束解析
+func TestScannerStop(t *testing.T) {
+	h := &testHandler{stop: "segment b.ts k"}
+	err := NewScanner(strings.NewReader(testScannerPlayList), Strict).Scan(h)
+	if !errors.Is(err, errTestStop) {
+		t.Fatalf("got %v, want errTestStop", err)
+	}
+	if len(h.events) != 4 {
+		t.Fatalf("got %q", h.events)
+	}
+}
+
+// 和Decode的结果一样
+func TestScannerDecode(t *testing.T) {
+	p, err := ParseMediaPlayList(strings.NewReader(testMediaPlayList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := new(segmentHandler)
+	if err := NewScanner(strings.NewReader(testMediaPlayList), Strict).Scan(h); err != nil {
+		t.Fatal(err)
+	}
+	h.end.MediaSegment = h.segments
+	if s, want := encodeString(t, h.end), encodeString(t, p); s != want {
+		t.Fatalf("got\n%s\nwant\n%s", s, want)
+	}
+}
+
+// 保存所有的片段
+type segmentHandler struct {
+	segments []MediaSegment
+	end      *MediaPlayList
+}
+
+func (h *segmentHandler) OnHeader(p *MediaPlayList) error { return nil }
+
+func (h *segmentHandler) OnDateRange(r *EXT_X_DATERANGE) error { return nil }
+
+func (h *segmentHandler) OnSegment(s *MediaSegment) error {
+	h.segments = append(h.segments, *s)
+	return nil
+}
+
+func (h *segmentHandler) OnEnd(p *MediaPlayList) error {
+	h.end = p
+	return nil
+}
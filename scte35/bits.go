@@ -0,0 +1,88 @@
+package scte35
+
+// 按位读取，出错后都返回0，最后检查err
+type bitReader struct {
+	b   []byte
+	i   int // 当前的位
+	err error
+}
+
+// 读取n位，n不大于64
+func (r *bitReader) read(n int) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if r.i+n > len(r.b)*8 {
+		r.err = ErrShortData
+		r.i = len(r.b) * 8
+		return 0
+	}
+	var v uint64
+	for k := 0; k < n; k++ {
+		v = v<<1 | uint64(r.b[r.i/8]>>(7-uint(r.i%8))&1)
+		r.i++
+	}
+	return v
+}
+
+func (r *bitReader) flag() bool {
+	return r.read(1) == 1
+}
+
+// 读取n个字节，必须是字节对齐的
+func (r *bitReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	i := r.i / 8
+	if i+n > len(r.b) {
+		r.err = ErrShortData
+		r.i = len(r.b) * 8
+		return nil
+	}
+	r.i += n * 8
+	return append([]byte(nil), r.b[i:i+n]...)
+}
+
+// 剩下的字节数
+func (r *bitReader) left() int {
+	return len(r.b) - (r.i+7)/8
+}
+
+// 按位写入
+type bitWriter struct {
+	b []byte
+	n int // 已经写入的位
+}
+
+// 写入v的低n位
+func (w *bitWriter) write(v uint64, n int) {
+	for k := n - 1; k >= 0; k-- {
+		if w.n%8 == 0 {
+			w.b = append(w.b, 0)
+		}
+		if v>>uint(k)&1 == 1 {
+			w.b[len(w.b)-1] |= 1 << (7 - uint(w.n%8))
+		}
+		w.n++
+	}
+}
+
+func (w *bitWriter) flag(b bool) {
+	if b {
+		w.write(1, 1)
+	} else {
+		w.write(0, 1)
+	}
+}
+
+// reserved的位都是1
+func (w *bitWriter) reserved(n int) {
+	w.write(1<<uint(n)-1, n)
+}
+
+// 写入b，必须是字节对齐的
+func (w *bitWriter) bytes(b []byte) {
+	w.b = append(w.b, b...)
+	w.n += len(b) * 8
+}
@@ -0,0 +1,243 @@
+package scte35
+
+// splice_command_type
+const (
+	SpliceNullType           = 0x00
+	SpliceScheduleType       = 0x04
+	SpliceInsertType         = 0x05
+	TimeSignalType           = 0x06
+	BandwidthReservationType = 0x07
+	PrivateCommandType       = 0xFF
+)
+
+// splice_command，SpliceNull，SpliceInsert，TimeSignal，
+// BandwidthReservation，PrivateCommand，其他的是RawCommand
+type SpliceCommand interface {
+	Type() uint8
+	decode(r *bitReader)
+	encode(w *bitWriter)
+}
+
+func newCommand(t uint8) SpliceCommand {
+	switch t {
+	case SpliceNullType:
+		return new(SpliceNull)
+	case SpliceInsertType:
+		return new(SpliceInsert)
+	case TimeSignalType:
+		return new(TimeSignal)
+	case BandwidthReservationType:
+		return new(BandwidthReservation)
+	case PrivateCommandType:
+		return new(PrivateCommand)
+	default:
+		return &RawCommand{CommandType: t}
+	}
+}
+
+// splice_time()
+type SpliceTime struct {
+	TimeSpecified bool
+	PTSTime       uint64 // 33位
+}
+
+func (t *SpliceTime) decode(r *bitReader) {
+	t.TimeSpecified = r.flag()
+	if t.TimeSpecified {
+		r.read(6)
+		t.PTSTime = r.read(33)
+	} else {
+		r.read(7)
+	}
+}
+
+func (t *SpliceTime) encode(w *bitWriter) {
+	w.flag(t.TimeSpecified)
+	if t.TimeSpecified {
+		w.reserved(6)
+		w.write(t.PTSTime, 33)
+	} else {
+		w.reserved(7)
+	}
+}
+
+// break_duration()
+type BreakDuration struct {
+	AutoReturn bool
+	Duration   uint64 // 33位，90kHz
+}
+
+// splice_null()
+type SpliceNull struct{}
+
+func (c *SpliceNull) Type() uint8 {
+	return SpliceNullType
+}
+
+func (c *SpliceNull) decode(r *bitReader) {}
+
+func (c *SpliceNull) encode(w *bitWriter) {}
+
+// splice_insert()的一个component
+type SpliceComponent struct {
+	ComponentTag uint8
+	SpliceTime   SpliceTime // 不是SpliceImmediateFlag
+}
+
+// splice_insert()
+type SpliceInsert struct {
+	SpliceEventID              uint32
+	SpliceEventCancelIndicator bool
+	OutOfNetworkIndicator      bool
+	ProgramSpliceFlag          bool
+	SpliceImmediateFlag        bool
+	SpliceTime                 SpliceTime        // ProgramSpliceFlag并且不是SpliceImmediateFlag
+	Components                 []SpliceComponent // 不是ProgramSpliceFlag
+	BreakDuration              *BreakDuration    // nil表示duration_flag是0
+	UniqueProgramID            uint16
+	AvailNum                   uint8
+	AvailsExpected             uint8
+}
+
+func (c *SpliceInsert) Type() uint8 {
+	return SpliceInsertType
+}
+
+func (c *SpliceInsert) decode(r *bitReader) {
+	c.SpliceEventID = uint32(r.read(32))
+	c.SpliceEventCancelIndicator = r.flag()
+	r.read(7)
+	if c.SpliceEventCancelIndicator {
+		return
+	}
+	c.OutOfNetworkIndicator = r.flag()
+	c.ProgramSpliceFlag = r.flag()
+	durationFlag := r.flag()
+	c.SpliceImmediateFlag = r.flag()
+	r.read(4)
+	if c.ProgramSpliceFlag {
+		if !c.SpliceImmediateFlag {
+			c.SpliceTime.decode(r)
+		}
+	} else {
+		n := int(r.read(8))
+		for i := 0; i < n && r.err == nil; i++ {
+			var sc SpliceComponent
+			sc.ComponentTag = uint8(r.read(8))
+			if !c.SpliceImmediateFlag {
+				sc.SpliceTime.decode(r)
+			}
+			c.Components = append(c.Components, sc)
+		}
+	}
+	if durationFlag {
+		c.BreakDuration = new(BreakDuration)
+		c.BreakDuration.AutoReturn = r.flag()
+		r.read(6)
+		c.BreakDuration.Duration = r.read(33)
+	}
+	c.UniqueProgramID = uint16(r.read(16))
+	c.AvailNum = uint8(r.read(8))
+	c.AvailsExpected = uint8(r.read(8))
+}
+
+func (c *SpliceInsert) encode(w *bitWriter) {
+	w.write(uint64(c.SpliceEventID), 32)
+	w.flag(c.SpliceEventCancelIndicator)
+	w.reserved(7)
+	if c.SpliceEventCancelIndicator {
+		return
+	}
+	w.flag(c.OutOfNetworkIndicator)
+	w.flag(c.ProgramSpliceFlag)
+	w.flag(c.BreakDuration != nil)
+	w.flag(c.SpliceImmediateFlag)
+	w.reserved(4)
+	if c.ProgramSpliceFlag {
+		if !c.SpliceImmediateFlag {
+			c.SpliceTime.encode(w)
+		}
+	} else {
+		w.write(uint64(len(c.Components)), 8)
+		for i := 0; i < len(c.Components); i++ {
+			w.write(uint64(c.Components[i].ComponentTag), 8)
+			if !c.SpliceImmediateFlag {
+				c.Components[i].SpliceTime.encode(w)
+			}
+		}
+	}
+	if c.BreakDuration != nil {
+		w.flag(c.BreakDuration.AutoReturn)
+		w.reserved(6)
+		w.write(c.BreakDuration.Duration, 33)
+	}
+	w.write(uint64(c.UniqueProgramID), 16)
+	w.write(uint64(c.AvailNum), 8)
+	w.write(uint64(c.AvailsExpected), 8)
+}
+
+// time_signal()
+type TimeSignal struct {
+	SpliceTime SpliceTime
+}
+
+func (c *TimeSignal) Type() uint8 {
+	return TimeSignalType
+}
+
+func (c *TimeSignal) decode(r *bitReader) {
+	c.SpliceTime.decode(r)
+}
+
+func (c *TimeSignal) encode(w *bitWriter) {
+	c.SpliceTime.encode(w)
+}
+
+// bandwidth_reservation()
+type BandwidthReservation struct{}
+
+func (c *BandwidthReservation) Type() uint8 {
+	return BandwidthReservationType
+}
+
+func (c *BandwidthReservation) decode(r *bitReader) {}
+
+func (c *BandwidthReservation) encode(w *bitWriter) {}
+
+// private_command()
+type PrivateCommand struct {
+	Identifier uint32
+	Data       []byte
+}
+
+func (c *PrivateCommand) Type() uint8 {
+	return PrivateCommandType
+}
+
+func (c *PrivateCommand) decode(r *bitReader) {
+	c.Identifier = uint32(r.read(32))
+	c.Data = r.bytes(r.left())
+}
+
+func (c *PrivateCommand) encode(w *bitWriter) {
+	w.write(uint64(c.Identifier), 32)
+	w.bytes(c.Data)
+}
+
+// 不解析的splice_command，比如splice_schedule()
+type RawCommand struct {
+	CommandType uint8
+	Data        []byte
+}
+
+func (c *RawCommand) Type() uint8 {
+	return c.CommandType
+}
+
+func (c *RawCommand) decode(r *bitReader) {
+	c.Data = r.bytes(r.left())
+}
+
+func (c *RawCommand) encode(w *bitWriter) {
+	w.bytes(c.Data)
+}
@@ -0,0 +1,26 @@
+package scte35
+
+// CRC-32/MPEG-2，多项式0x04C11DB7，初始值0xFFFFFFFF，不反转，ISO/IEC 13818-1 Annex A
+var crcTable = func() (t [256]uint32) {
+	for i := 0; i < 256; i++ {
+		c := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if c&0x80000000 != 0 {
+				c = c<<1 ^ 0x04C11DB7
+			} else {
+				c <<= 1
+			}
+		}
+		t[i] = c
+	}
+	return
+}()
+
+// 计算b的CRC_32，包括CRC_32在内的整个section的结果是0
+func CRC32(b []byte) uint32 {
+	c := uint32(0xFFFFFFFF)
+	for _, v := range b {
+		c = c<<8 ^ crcTable[byte(c>>24)^v]
+	}
+	return c
+}
@@ -0,0 +1,298 @@
+package scte35
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	m3u8 "github.com/qq51529210/m3u8"
+)
+
+// 常见的广告标记，不是RFC8216定义的
+const (
+	TagEXT_X_CUE_OUT      = "#EXT-X-CUE-OUT"
+	TagEXT_X_CUE_OUT_CONT = "#EXT-X-CUE-OUT-CONT"
+	TagEXT_X_CUE_IN       = "#EXT-X-CUE-IN"
+	TagEXT_OATCLS_SCTE35  = "#EXT-OATCLS-SCTE35"
+)
+
+// section在#EXT-X-DATERANGE中使用的属性
+type CueType int
+
+const (
+	// SCTE35-CMD
+	CueCmd CueType = iota
+	// SCTE35-OUT，广告开始
+	CueOut
+	// SCTE35-IN，广告结束
+	CueIn
+)
+
+// 根据splice_insert的out_of_network_indicator，
+// 或者time_signal的segmentation_type_id判断是OUT还是IN
+func (s *SpliceInfoSection) CueType() CueType {
+	switch c := s.Command.(type) {
+	case *SpliceInsert:
+		if c.SpliceEventCancelIndicator {
+			return CueCmd
+		}
+		if c.OutOfNetworkIndicator {
+			return CueOut
+		}
+		return CueIn
+	case *TimeSignal:
+		d := s.Segmentation()
+		if d == nil || d.SegmentationEventCancelIndicator {
+			return CueCmd
+		}
+		switch d.SegmentationTypeID {
+		case BreakStart,
+			ProviderAdvertisementStart,
+			DistributorAdvertisementStart,
+			ProviderPlacementOpportunityStart,
+			DistributorPlacementOpportunityStart,
+			ProviderAdBlockStart,
+			DistributorAdBlockStart:
+			return CueOut
+		case BreakEnd,
+			ProviderAdvertisementEnd,
+			DistributorAdvertisementEnd,
+			ProviderPlacementOpportunityEnd,
+			DistributorPlacementOpportunityEnd,
+			ProviderAdBlockEnd,
+			DistributorAdBlockEnd:
+			return CueIn
+		}
+	}
+	return CueCmd
+}
+
+// 返回break_duration或者segmentation_duration，秒，没有返回false
+func (s *SpliceInfoSection) Duration() (float64, bool) {
+	switch c := s.Command.(type) {
+	case *SpliceInsert:
+		if c.BreakDuration != nil {
+			return Seconds(c.BreakDuration.Duration), true
+		}
+	case *TimeSignal:
+		d := s.Segmentation()
+		if d != nil && d.SegmentationDuration != nil {
+			return Seconds(*d.SegmentationDuration), true
+		}
+	}
+	return 0, false
+}
+
+// 返回section对应的ID，splice_event_id或者segmentation_event_id，没有返回空
+func (s *SpliceInfoSection) EventID() string {
+	if c, ok := s.Command.(*SpliceInsert); ok {
+		return fmt.Sprintf("splice-%d", c.SpliceEventID)
+	}
+	if d := s.Segmentation(); d != nil {
+		return fmt.Sprintf("segmentation-%d", d.SegmentationEventID)
+	}
+	return ""
+}
+
+// 返回s对应的#EXT-X-DATERANGE，根据CueType设置SCTE35-CMD，SCTE35-OUT或者SCTE35-IN，
+// SCTE35-OUT有时长时设置PLANNED-DURATION
+func DateRange(s *SpliceInfoSection, id string, start time.Time) (*m3u8.EXT_X_DATERANGE, error) {
+	b, err := s.Encode()
+	if err != nil {
+		return nil, err
+	}
+	r := &m3u8.EXT_X_DATERANGE{ID: id, START_DATE: start}
+	switch s.CueType() {
+	case CueOut:
+		r.SCTE35_OUT = b
+		if d, ok := s.Duration(); ok {
+			r.PLANNED_DURATION = &d
+		}
+	case CueIn:
+		r.SCTE35_IN = b
+	default:
+		r.SCTE35_CMD = b
+	}
+	return r, nil
+}
+
+// 把#EXT-X-CUE-OUT，#EXT-X-CUE-IN和#EXT-OATCLS-SCTE35转换成#EXT-X-DATERANGE，
+// #EXT-OATCLS-SCTE35的数据用在后面的#EXT-X-CUE-OUT或者#EXT-X-CUE-IN上。
+// CUE-IN使用和CUE-OUT一样的ID和START-DATE，DURATION是实际的时长，RFC8216 4.3.2.7.1
+type CueConverter struct {
+	n      int                   // 生成ID
+	out    *m3u8.EXT_X_DATERANGE // 没有CUE-IN的CUE-OUT
+	scte35 []byte                // 还没有使用的#EXT-OATCLS-SCTE35
+}
+
+// 转换一行，t是这一行后面的片段的开始时间。
+// 返回nil表示不需要输出#EXT-X-DATERANGE，false表示不是cue的tag
+func (c *CueConverter) Convert(line string, t time.Time) (*m3u8.EXT_X_DATERANGE, bool, error) {
+	tag, value := m3u8.ParseLine([]byte(line))
+	switch string(tag) {
+	case TagEXT_OATCLS_SCTE35:
+		b, err := decodeString(string(value))
+		if err == nil {
+			_, err = Decode(b)
+		}
+		if err != nil {
+			return nil, true, fmt.Errorf("%s %w", TagEXT_OATCLS_SCTE35, err)
+		}
+		c.scte35 = b
+		return nil, true, nil
+	case TagEXT_X_CUE_OUT:
+		r, err := c.cueOut(value, t)
+		if err != nil {
+			return nil, true, fmt.Errorf("%s %w", TagEXT_X_CUE_OUT, err)
+		}
+		return r, true, nil
+	case TagEXT_X_CUE_OUT_CONT:
+		return nil, true, nil
+	case TagEXT_X_CUE_IN:
+		if c.out == nil {
+			c.scte35 = nil
+			return nil, true, fmt.Errorf("%s without %s", TagEXT_X_CUE_IN, TagEXT_X_CUE_OUT)
+		}
+		d := t.Sub(c.out.START_DATE).Seconds()
+		r := &m3u8.EXT_X_DATERANGE{
+			ID:         c.out.ID,
+			START_DATE: c.out.START_DATE,
+			DURATION:   &d,
+			SCTE35_IN:  c.scte35,
+		}
+		c.out = nil
+		c.scte35 = nil
+		return r, true, nil
+	}
+	return nil, false, nil
+}
+
+// #EXT-X-CUE-OUT:<duration>或者#EXT-X-CUE-OUT:DURATION=<duration>[,SCTE35=<base64>]
+func (c *CueConverter) cueOut(value []byte, t time.Time) (*m3u8.EXT_X_DATERANGE, error) {
+	var duration string
+	if strings.IndexByte(string(value), '=') < 0 {
+		duration = string(value)
+	} else {
+		attr, err := m3u8.ParseAttribute(value)
+		if err != nil {
+			return nil, err
+		}
+		duration = attr["DURATION"]
+		if s := attr["SCTE35"]; s != "" {
+			c.scte35, err = decodeString(s)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	r := &m3u8.EXT_X_DATERANGE{START_DATE: t, SCTE35_OUT: c.scte35}
+	if duration != "" {
+		d, err := m3u8.ParseDecimalFloat(duration)
+		if err != nil {
+			return nil, err
+		}
+		r.PLANNED_DURATION = &d
+	}
+	if len(c.scte35) > 0 {
+		s, err := Decode(c.scte35)
+		if err != nil {
+			return nil, err
+		}
+		r.ID = s.EventID()
+		if r.PLANNED_DURATION == nil {
+			if d, ok := s.Duration(); ok {
+				r.PLANNED_DURATION = &d
+			}
+		}
+	}
+	if r.ID == "" {
+		c.n++
+		r.ID = fmt.Sprintf("cue-%d", c.n)
+	}
+	c.out = r
+	c.scte35 = nil
+	return r, nil
+}
+
+// 片段没有开始时间
+var ErrMissingDateTime = errors.New("missing " + m3u8.TagEXT_X_PROGRAM_DATE_TIME)
+
+// 把p中的cue的tag转换成片段的#EXT-X-DATERANGE，并从Unknown中删除。
+// 片段的开始时间使用前面的#EXT-X-PROGRAM-DATE-TIME加上后面片段的时长。
+// 第一个片段前面的tag转换到第一个片段，最后一个片段后面的转换到最后一个片段
+func (c *CueConverter) ConvertMediaPlayList(p *m3u8.MediaPlayList) error {
+	if len(p.MediaSegment) < 1 {
+		return nil
+	}
+	var t time.Time
+	var err error
+	for i := 0; i < len(p.MediaSegment); i++ {
+		s := &p.MediaSegment[i]
+		if !s.EXT_X_PROGRAM_DATE_TIME.IsZero() {
+			t = s.EXT_X_PROGRAM_DATE_TIME
+		}
+		if i == 0 {
+			p.Unknown, err = c.convertLines(p.Unknown, p.Tags, s, t, false)
+			if err != nil {
+				return err
+			}
+		}
+		s.Unknown, err = c.convertLines(s.Unknown, s.Tags, s, t, true)
+		if err != nil {
+			return err
+		}
+		if !t.IsZero() {
+			t = t.Add(time.Duration(s.EXTINF.DURATION * float64(time.Second)))
+		}
+	}
+	p.TrailingUnknown, err = c.convertLines(p.TrailingUnknown, p.TrailingTags, &p.MediaSegment[len(p.MediaSegment)-1], t, false)
+	return err
+}
+
+// 转换lines，返回不是cue的行。tags是lines所在部分的自定义tag，
+// same表示lines是s的，转换出来的#EXT-X-DATERANGE占用cue原来的位置，
+// 否则cue的位置被删除，后面的行和tag的Index要减去前面删除的行数
+func (c *CueConverter) convertLines(lines []m3u8.UnknownLine, tags []m3u8.CustomTag, s *m3u8.MediaSegment, t time.Time, same bool) ([]m3u8.UnknownLine, error) {
+	var unknown []m3u8.UnknownLine
+	var removed []int // 删除的行的Index
+	for _, u := range lines {
+		line := u.Line
+		if t.IsZero() && isCueTag(line) {
+			return nil, fmt.Errorf("line %d: %w", s.Line, ErrMissingDateTime)
+		}
+		r, ok, err := c.Convert(line, t)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", s.Line, err)
+		}
+		if !ok {
+			u.Index -= len(removed)
+			unknown = append(unknown, u)
+			continue
+		}
+		if r != nil {
+			s.EXT_X_DATERANGE = append(s.EXT_X_DATERANGE, *r)
+			if same {
+				continue
+			}
+		}
+		removed = append(removed, u.Index)
+	}
+	for i := 0; i < len(tags); i++ {
+		n := 0
+		for n < len(removed) && removed[n] < tags[i].Index {
+			n++
+		}
+		tags[i].Index -= n
+	}
+	return unknown, nil
+}
+
+func isCueTag(line string) bool {
+	tag, _ := m3u8.ParseLine([]byte(line))
+	switch string(tag) {
+	case TagEXT_OATCLS_SCTE35, TagEXT_X_CUE_OUT, TagEXT_X_CUE_OUT_CONT, TagEXT_X_CUE_IN:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package scte35
+
+import "fmt"
+
+// splice_descriptor_tag
+const (
+	AvailDescriptorTag        = 0x00
+	DTMFDescriptorTag         = 0x01
+	SegmentationDescriptorTag = 0x02
+	TimeDescriptorTag         = 0x03
+	AudioDescriptorTag        = 0x04
+)
+
+// SCTE-35定义的descriptor的identifier，"CUEI"
+const CUEI = 0x43554549
+
+// splice_descriptor()，除了SegmentationDescriptor，其他的是RawDescriptor
+type SpliceDescriptor interface {
+	Tag() uint8
+	decode(r *bitReader)
+	encode(w *bitWriter)
+}
+
+// 读取一个splice_descriptor()
+func decodeDescriptor(r *bitReader) (SpliceDescriptor, error) {
+	tag := uint8(r.read(8))
+	n := int(r.read(8))
+	b := r.bytes(n)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if n < 4 {
+		return nil, fmt.Errorf("splice_descriptor_tag %d: %w", tag, ErrShortData)
+	}
+	var d SpliceDescriptor
+	dr := &bitReader{b: b}
+	id := uint32(dr.read(32))
+	if tag == SegmentationDescriptorTag && id == CUEI {
+		d = &SegmentationDescriptor{Identifier: id}
+	} else {
+		d = &RawDescriptor{DescriptorTag: tag, Identifier: id}
+	}
+	d.decode(dr)
+	if dr.err != nil {
+		return nil, fmt.Errorf("splice_descriptor_tag %d: %w", tag, dr.err)
+	}
+	return d, nil
+}
+
+// 写入一个splice_descriptor()
+func encodeDescriptor(w *bitWriter, d SpliceDescriptor) error {
+	var b bitWriter
+	d.encode(&b)
+	if len(b.b) > 0xFF {
+		return fmt.Errorf("splice_descriptor_tag %d: too long", d.Tag())
+	}
+	w.write(uint64(d.Tag()), 8)
+	w.write(uint64(len(b.b)), 8)
+	w.bytes(b.b)
+	return nil
+}
+
+// 不解析的splice_descriptor()
+type RawDescriptor struct {
+	DescriptorTag uint8
+	Identifier    uint32
+	Data          []byte
+}
+
+func (d *RawDescriptor) Tag() uint8 {
+	return d.DescriptorTag
+}
+
+func (d *RawDescriptor) decode(r *bitReader) {
+	d.Data = r.bytes(r.left())
+}
+
+func (d *RawDescriptor) encode(w *bitWriter) {
+	w.write(uint64(d.Identifier), 32)
+	w.bytes(d.Data)
+}
+
+// segmentation_descriptor()的一个component
+type SegmentationComponent struct {
+	ComponentTag uint8
+	PTSOffset    uint64 // 33位
+}
+
+// sub_segment_num和sub_segments_expected
+type SubSegment struct {
+	Num      uint8
+	Expected uint8
+}
+
+// segmentation_type_id
+const (
+	ProgramStart                           = 0x10
+	ProgramEnd                             = 0x11
+	ChapterStart                           = 0x20
+	ChapterEnd                             = 0x21
+	BreakStart                             = 0x22
+	BreakEnd                               = 0x23
+	ProviderAdvertisementStart             = 0x30
+	ProviderAdvertisementEnd               = 0x31
+	DistributorAdvertisementStart          = 0x32
+	DistributorAdvertisementEnd            = 0x33
+	ProviderPlacementOpportunityStart      = 0x34
+	ProviderPlacementOpportunityEnd        = 0x35
+	DistributorPlacementOpportunityStart   = 0x36
+	DistributorPlacementOpportunityEnd     = 0x37
+	ProviderOverlayPlacementOpportunity    = 0x38
+	DistributorOverlayPlacementOpportunity = 0x3A
+	ProviderAdBlockStart                   = 0x44
+	ProviderAdBlockEnd                     = 0x45
+	DistributorAdBlockStart                = 0x46
+	DistributorAdBlockEnd                  = 0x47
+)
+
+// segmentation_descriptor()
+type SegmentationDescriptor struct {
+	Identifier                             uint32 // 0表示CUEI
+	SegmentationEventID                    uint32
+	SegmentationEventCancelIndicator       bool
+	SegmentationEventIDComplianceIndicator bool
+	ProgramSegmentationFlag                bool
+	DeliveryNotRestrictedFlag              bool
+	// 下面4个在不是DeliveryNotRestrictedFlag时有效
+	WebDeliveryAllowedFlag bool
+	NoRegionalBlackoutFlag bool
+	ArchiveAllowedFlag     bool
+	DeviceRestrictions     uint8                   // 2位
+	Components             []SegmentationComponent // 不是ProgramSegmentationFlag
+	SegmentationDuration   *uint64                 // 40位，90kHz，nil表示没有
+	SegmentationUPIDType   uint8
+	SegmentationUPID       []byte
+	SegmentationTypeID     uint8
+	SegmentNum             uint8
+	SegmentsExpected       uint8
+	SubSegment             *SubSegment // nil表示没有
+}
+
+func (d *SegmentationDescriptor) Tag() uint8 {
+	return SegmentationDescriptorTag
+}
+
+func (d *SegmentationDescriptor) decode(r *bitReader) {
+	d.SegmentationEventID = uint32(r.read(32))
+	d.SegmentationEventCancelIndicator = r.flag()
+	d.SegmentationEventIDComplianceIndicator = r.flag()
+	r.read(6)
+	if d.SegmentationEventCancelIndicator {
+		return
+	}
+	d.ProgramSegmentationFlag = r.flag()
+	durationFlag := r.flag()
+	d.DeliveryNotRestrictedFlag = r.flag()
+	if d.DeliveryNotRestrictedFlag {
+		r.read(5)
+	} else {
+		d.WebDeliveryAllowedFlag = r.flag()
+		d.NoRegionalBlackoutFlag = r.flag()
+		d.ArchiveAllowedFlag = r.flag()
+		d.DeviceRestrictions = uint8(r.read(2))
+	}
+	if !d.ProgramSegmentationFlag {
+		n := int(r.read(8))
+		for i := 0; i < n && r.err == nil; i++ {
+			var c SegmentationComponent
+			c.ComponentTag = uint8(r.read(8))
+			r.read(7)
+			c.PTSOffset = r.read(33)
+			d.Components = append(d.Components, c)
+		}
+	}
+	if durationFlag {
+		n := r.read(40)
+		d.SegmentationDuration = &n
+	}
+	d.SegmentationUPIDType = uint8(r.read(8))
+	d.SegmentationUPID = r.bytes(int(r.read(8)))
+	d.SegmentationTypeID = uint8(r.read(8))
+	d.SegmentNum = uint8(r.read(8))
+	d.SegmentsExpected = uint8(r.read(8))
+	// 以前的版本没有sub_segment_num和sub_segments_expected
+	if hasSubSegment(d.SegmentationTypeID) && r.left() >= 2 {
+		d.SubSegment = new(SubSegment)
+		d.SubSegment.Num = uint8(r.read(8))
+		d.SubSegment.Expected = uint8(r.read(8))
+	}
+}
+
+func (d *SegmentationDescriptor) encode(w *bitWriter) {
+	id := d.Identifier
+	if id == 0 {
+		id = CUEI
+	}
+	w.write(uint64(id), 32)
+	w.write(uint64(d.SegmentationEventID), 32)
+	w.flag(d.SegmentationEventCancelIndicator)
+	w.flag(d.SegmentationEventIDComplianceIndicator)
+	w.reserved(6)
+	if d.SegmentationEventCancelIndicator {
+		return
+	}
+	w.flag(d.ProgramSegmentationFlag)
+	w.flag(d.SegmentationDuration != nil)
+	w.flag(d.DeliveryNotRestrictedFlag)
+	if d.DeliveryNotRestrictedFlag {
+		w.reserved(5)
+	} else {
+		w.flag(d.WebDeliveryAllowedFlag)
+		w.flag(d.NoRegionalBlackoutFlag)
+		w.flag(d.ArchiveAllowedFlag)
+		w.write(uint64(d.DeviceRestrictions), 2)
+	}
+	if !d.ProgramSegmentationFlag {
+		w.write(uint64(len(d.Components)), 8)
+		for i := 0; i < len(d.Components); i++ {
+			w.write(uint64(d.Components[i].ComponentTag), 8)
+			w.reserved(7)
+			w.write(d.Components[i].PTSOffset, 33)
+		}
+	}
+	if d.SegmentationDuration != nil {
+		w.write(*d.SegmentationDuration, 40)
+	}
+	w.write(uint64(d.SegmentationUPIDType), 8)
+	w.write(uint64(len(d.SegmentationUPID)), 8)
+	w.bytes(d.SegmentationUPID)
+	w.write(uint64(d.SegmentationTypeID), 8)
+	w.write(uint64(d.SegmentNum), 8)
+	w.write(uint64(d.SegmentsExpected), 8)
+	if d.SubSegment != nil {
+		w.write(uint64(d.SubSegment.Num), 8)
+		w.write(uint64(d.SubSegment.Expected), 8)
+	}
+}
+
+// 这些segmentation_type_id有sub_segment_num和sub_segments_expected
+func hasSubSegment(id uint8) bool {
+	switch id {
+	case ProviderPlacementOpportunityStart,
+		DistributorPlacementOpportunityStart,
+		ProviderOverlayPlacementOpportunity,
+		DistributorOverlayPlacementOpportunity,
+		ProviderAdBlockStart,
+		DistributorAdBlockStart:
+		return true
+	}
+	return false
+}
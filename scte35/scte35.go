@@ -0,0 +1,255 @@
+// SCTE-35的splice_info_section，ANSI/SCTE 35 2022 9.6。
+// #EXT-X-DATERANGE的SCTE35-CMD，SCTE35-OUT和SCTE35-IN，
+// 还有#EXT-OATCLS-SCTE35，#EXT-X-CUE-OUT-CONT中的SCTE35都是splice_info_section
+package scte35
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// splice_info_section的table_id
+const TableID = 0xFC
+
+// PTS的时钟频率
+const TimeScale = 90000
+
+var (
+	// 数据不完整
+	ErrShortData = errors.New("short splice_info_section")
+	// table_id不是0xFC
+	ErrTableID = errors.New("invalid table_id")
+	// CRC_32不对
+	ErrCRC = errors.New("CRC_32 mismatch")
+)
+
+// splice_info_section
+type SpliceInfoSection struct {
+	SAPType             uint8 // 2位，3表示没有指定
+	ProtocolVersion     uint8
+	EncryptedPacket     bool
+	EncryptionAlgorithm uint8  // 6位
+	PTSAdjustment       uint64 // 33位
+	CWIndex             uint8
+	Tier                uint16 // 12位，0xFFF表示没有限制
+	Command             SpliceCommand
+	Descriptors         []SpliceDescriptor
+	// EncryptedPacket时，不解析Command和Descriptors，
+	// 保存splice_command_length和后面到E_CRC_32的原始数据
+	EncryptedCommandLength uint16
+	Encrypted              []byte
+}
+
+// 返回一个没有加密，SAPType是3，Tier是0xFFF的section
+func New(cmd SpliceCommand, descriptors ...SpliceDescriptor) *SpliceInfoSection {
+	return &SpliceInfoSection{
+		SAPType:     3,
+		Tier:        0xFFF,
+		Command:     cmd,
+		Descriptors: descriptors,
+	}
+}
+
+// 解析splice_info_section，检查CRC_32，section_length后面多余的数据会忽略
+func Decode(b []byte) (*SpliceInfoSection, error) {
+	r := &bitReader{b: b}
+	if r.read(8) != TableID {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, ErrTableID
+	}
+	s := new(SpliceInfoSection)
+	// section_syntax_indicator，private_indicator
+	r.read(2)
+	s.SAPType = uint8(r.read(2))
+	n := int(r.read(12)) + 3
+	if r.err != nil {
+		return nil, r.err
+	}
+	if n > len(b) || n < 3+17 {
+		return nil, ErrShortData
+	}
+	b = b[:n]
+	if CRC32(b) != 0 {
+		return nil, ErrCRC
+	}
+	// 去掉CRC_32
+	r.b = b[:n-4]
+	s.ProtocolVersion = uint8(r.read(8))
+	s.EncryptedPacket = r.flag()
+	s.EncryptionAlgorithm = uint8(r.read(6))
+	s.PTSAdjustment = r.read(33)
+	s.CWIndex = uint8(r.read(8))
+	s.Tier = uint16(r.read(12))
+	cmdLength := int(r.read(12))
+	if s.EncryptedPacket {
+		s.EncryptedCommandLength = uint16(cmdLength)
+		s.Encrypted = r.bytes(r.left())
+		return s, r.err
+	}
+	cmdType := uint8(r.read(8))
+	if r.err != nil {
+		return nil, r.err
+	}
+	s.Command = newCommand(cmdType)
+	// 0xFFF是以前的版本，表示不知道长度
+	if cmdLength == 0xFFF {
+		s.Command.decode(r)
+	} else {
+		cr := &bitReader{b: r.bytes(cmdLength)}
+		if r.err != nil {
+			return nil, r.err
+		}
+		s.Command.decode(cr)
+		if cr.err != nil {
+			return nil, fmt.Errorf("splice_command_type %d: %w", cmdType, cr.err)
+		}
+	}
+	dr := &bitReader{b: r.bytes(int(r.read(16)))}
+	if r.err != nil {
+		return nil, r.err
+	}
+	for dr.left() > 0 {
+		d, err := decodeDescriptor(dr)
+		if err != nil {
+			return nil, err
+		}
+		s.Descriptors = append(s.Descriptors, d)
+	}
+	// 后面是alignment_stuffing，不需要
+	return s, nil
+}
+
+// 解析<hexadecimal-sequence>，0x开头可以省略
+func DecodeHex(s string) (*SpliceInfoSection, error) {
+	if len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		s = s[2:]
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(b)
+}
+
+// 解析base64
+func DecodeBase64(s string) (*SpliceInfoSection, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(b)
+}
+
+// 0x开头的按照hex，否则按照base64
+func DecodeString(s string) (*SpliceInfoSection, error) {
+	b, err := decodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(b)
+}
+
+func decodeString(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		return hex.DecodeString(s[2:])
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// 输出splice_info_section，计算所有的长度和CRC_32
+func (s *SpliceInfoSection) Encode() ([]byte, error) {
+	var body bitWriter
+	body.write(uint64(s.ProtocolVersion), 8)
+	body.flag(s.EncryptedPacket)
+	body.write(uint64(s.EncryptionAlgorithm), 6)
+	body.write(s.PTSAdjustment, 33)
+	body.write(uint64(s.CWIndex), 8)
+	body.write(uint64(s.Tier), 12)
+	if s.EncryptedPacket {
+		body.write(uint64(s.EncryptedCommandLength), 12)
+		body.bytes(s.Encrypted)
+	} else {
+		if s.Command == nil {
+			return nil, errors.New("missing splice command")
+		}
+		var cmd bitWriter
+		s.Command.encode(&cmd)
+		if len(cmd.b) >= 0xFFF {
+			return nil, errors.New("splice command too long")
+		}
+		body.write(uint64(len(cmd.b)), 12)
+		body.write(uint64(s.Command.Type()), 8)
+		body.bytes(cmd.b)
+		var desc bitWriter
+		for _, d := range s.Descriptors {
+			err := encodeDescriptor(&desc, d)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(desc.b) > 0xFFFF {
+			return nil, errors.New("splice descriptors too long")
+		}
+		body.write(uint64(len(desc.b)), 16)
+		body.bytes(desc.b)
+	}
+	// 加上CRC_32
+	n := len(body.b) + 4
+	if n > 0xFFF {
+		return nil, errors.New("splice_info_section too long")
+	}
+	var w bitWriter
+	w.write(TableID, 8)
+	// section_syntax_indicator是0，private_indicator是0
+	w.write(0, 2)
+	w.write(uint64(s.SAPType), 2)
+	w.write(uint64(n), 12)
+	w.bytes(body.b)
+	c := CRC32(w.b)
+	w.write(uint64(c), 32)
+	return w.b, nil
+}
+
+// 输出0x开头的<hexadecimal-sequence>
+func (s *SpliceInfoSection) EncodeHex() (string, error) {
+	b, err := s.Encode()
+	if err != nil {
+		return "", err
+	}
+	return "0x" + strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// 输出base64
+func (s *SpliceInfoSection) EncodeBase64() (string, error) {
+	b, err := s.Encode()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// 返回第一个SegmentationDescriptor，没有返回nil
+func (s *SpliceInfoSection) Segmentation() *SegmentationDescriptor {
+	for _, d := range s.Descriptors {
+		if sd, ok := d.(*SegmentationDescriptor); ok {
+			return sd
+		}
+	}
+	return nil
+}
+
+// PTS转换成秒
+func Seconds(pts uint64) float64 {
+	return float64(pts) / TimeScale
+}
+
+// 秒转换成PTS
+func PTS(seconds float64) uint64 {
+	return uint64(seconds*TimeScale + 0.5)
+}
@@ -0,0 +1,239 @@
+package scte35
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	m3u8 "github.com/qq51529210/m3u8"
+)
+
+const (
+	// splice_insert，OUT，有break_duration
+	testSpliceInsert = "/DAvAAAAAAAA///wFAVIAACPf+/+c2nALv4AUsz1AAAAAAAKAAhDVUVJAAABNWLbowo="
+	// time_signal，segmentation_type_id是0x34
+	testTimeSignal = "/DA0AAAAAAAA///wBQb+cr0AUAAeAhxDVUVJSAAAjn/PAAGlmbAICAAAAAAsoKGKNAIAmsnRfg=="
+	// time_signal，两个segmentation_descriptor
+	testTwoDescriptors = "/DBIAAAAAAAA///wBQb+ek2ItgAyAhdDVUVJSAAAGH+fCAgAAAAALMvDRBEAAAIXQ1VFSUgAABl/nwgIAAAAACyk26AQAACZcuND"
+)
+
+// 解析以后再输出，应该一模一样
+func TestRoundTrip(t *testing.T) {
+	for _, v := range []string{testSpliceInsert, testTimeSignal, testTwoDescriptors} {
+		s, err := DecodeBase64(v)
+		if err != nil {
+			t.Fatalf("%s: %v", v, err)
+		}
+		b, err := s.EncodeBase64()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != v {
+			t.Fatalf("got %s, want %s", b, v)
+		}
+		// 十六进制
+		h, err := s.EncodeHex()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DecodeString(h); err != nil {
+			t.Fatalf("%s: %v", h, err)
+		}
+	}
+}
+
+func TestSpliceInsert(t *testing.T) {
+	s, err := DecodeBase64(testSpliceInsert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := s.Command.(*SpliceInsert)
+	if !ok {
+		t.Fatalf("got %T, want *SpliceInsert", s.Command)
+	}
+	if c.SpliceEventID != 1207959695 || !c.OutOfNetworkIndicator || !c.ProgramSpliceFlag ||
+		!c.SpliceTime.TimeSpecified || c.SpliceTime.PTSTime != 1936310318 {
+		t.Fatalf("got %+v", c)
+	}
+	if c.BreakDuration == nil || !c.BreakDuration.AutoReturn || c.BreakDuration.Duration != 5426421 {
+		t.Fatalf("got break duration %+v", c.BreakDuration)
+	}
+	if s.CueType() != CueOut || s.EventID() != "splice-1207959695" {
+		t.Fatalf("got cue type %v, event id %s", s.CueType(), s.EventID())
+	}
+	if d, ok := s.Duration(); !ok || d != Seconds(5426421) {
+		t.Fatalf("got duration %v %v", d, ok)
+	}
+}
+
+func TestTimeSignal(t *testing.T) {
+	s, err := DecodeBase64(testTimeSignal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := s.Command.(*TimeSignal)
+	if !ok {
+		t.Fatalf("got %T, want *TimeSignal", s.Command)
+	}
+	if c.SpliceTime.PTSTime != 1924989008 {
+		t.Fatalf("got %+v", c)
+	}
+	d := s.Segmentation()
+	if d == nil || d.SegmentationEventID != 1207959694 || d.SegmentationTypeID != ProviderPlacementOpportunityStart ||
+		d.SegmentationUPIDType != 8 || len(d.SegmentationUPID) != 8 || d.SegmentNum != 2 {
+		t.Fatalf("got %+v", d)
+	}
+	if s.CueType() != CueOut || s.EventID() != "segmentation-1207959694" {
+		t.Fatalf("got cue type %v, event id %s", s.CueType(), s.EventID())
+	}
+	if duration, ok := s.Duration(); !ok || duration != 307 {
+		t.Fatalf("got duration %v %v", duration, ok)
+	}
+	s, err = DecodeBase64(testTwoDescriptors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Descriptors) != 2 {
+		t.Fatalf("got %d descriptors, want 2", len(s.Descriptors))
+	}
+	// 第一个是ProgramEnd，不是OUT或者IN
+	if s.CueType() != CueCmd {
+		t.Fatalf("got cue type %v", s.CueType())
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	b, err := base64.StdEncoding.DecodeString(testSpliceInsert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 修改一个字节，CRC_32不对
+	b[10] ^= 0xFF
+	if _, err := Decode(b); !errors.Is(err, ErrCRC) {
+		t.Fatalf("got %v, want ErrCRC", err)
+	}
+	b[10] ^= 0xFF
+	if _, err := Decode(b[:20]); !errors.Is(err, ErrShortData) {
+		t.Fatalf("got %v, want ErrShortData", err)
+	}
+	b[0] = 0
+	if _, err := Decode(b); !errors.Is(err, ErrTableID) {
+		t.Fatalf("got %v, want ErrTableID", err)
+	}
+}
+
+func TestCRC32(t *testing.T) {
+	// CRC-32/MPEG-2的校验值
+	if c := CRC32([]byte("123456789")); c != 0x0376E6E7 {
+		t.Fatalf("got %08X", c)
+	}
+}
+
+func TestCueConverter(t *testing.T) {
+	src := "#EXTM3U\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00.000Z\n" +
+		"#EXTINF:10,\n" +
+		"a.ts\n" +
+		"#EXT-X-CUE-OUT:30\n" +
+		"#EXTINF:10,\n" +
+		"b.ts\n" +
+		"#EXT-X-CUE-OUT-CONT:10/30\n" +
+		"#EXTINF:10,\n" +
+		"c.ts\n" +
+		"#EXTINF:10,\n" +
+		"d.ts\n" +
+		"#EXT-X-CUE-IN\n" +
+		"#EXTINF:10,\n" +
+		"e.ts\n"
+	p, err := m3u8.ParseMediaPlayList(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c CueConverter
+	if err := c.ConvertMediaPlayList(p); err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if _, err := p.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "#EXTM3U\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00.000Z\n" +
+		"#EXTINF:10,\n" +
+		"a.ts\n" +
+		"#EXT-X-DATERANGE:ID=\"cue-1\",START-DATE=\"2020-01-01T00:00:10.000Z\",PLANNED-DURATION=30\n" +
+		"#EXTINF:10,\n" +
+		"b.ts\n" +
+		"#EXTINF:10,\n" +
+		"c.ts\n" +
+		"#EXTINF:10,\n" +
+		"d.ts\n" +
+		"#EXT-X-DATERANGE:ID=\"cue-1\",START-DATE=\"2020-01-01T00:00:10.000Z\",DURATION=30\n" +
+		"#EXTINF:10,\n" +
+		"e.ts\n"
+	if b.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+// cue前后的注释输出在原来的位置，没有转换成#EXT-X-DATERANGE的cue后面的行的位置要前移
+func TestCueConverterUnknownIndex(t *testing.T) {
+	src := "#EXTM3U\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00.000Z\n" +
+		"#EXTINF:10,\n" +
+		"a.ts\n" +
+		"# before out\n" +
+		"#EXT-X-CUE-OUT:20\n" +
+		"# after out\n" +
+		"#EXTINF:10,\n" +
+		"b.ts\n" +
+		"#EXT-X-CUE-OUT-CONT:10/20\n" +
+		"# after cont\n" +
+		"#EXT-X-BITRATE:100\n" +
+		"#EXTINF:10,\n" +
+		"c.ts\n" +
+		"#EXT-X-CUE-IN\n" +
+		"# after in\n" +
+		"#EXTINF:10,\n" +
+		"d.ts\n"
+	p, err := m3u8.ParseMediaPlayList(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c CueConverter
+	if err := c.ConvertMediaPlayList(p); err != nil {
+		t.Fatal(err)
+	}
+	if u := p.MediaSegment[2].Unknown; len(u) != 1 || u[0].Index != 0 {
+		t.Fatalf("got %v", u)
+	}
+	var b strings.Builder
+	if _, err := p.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "#EXTM3U\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00.000Z\n" +
+		"#EXTINF:10,\n" +
+		"a.ts\n" +
+		"# before out\n" +
+		"#EXT-X-DATERANGE:ID=\"cue-1\",START-DATE=\"2020-01-01T00:00:10.000Z\",PLANNED-DURATION=20\n" +
+		"# after out\n" +
+		"#EXTINF:10,\n" +
+		"b.ts\n" +
+		"# after cont\n" +
+		"#EXT-X-BITRATE:100\n" +
+		"#EXTINF:10,\n" +
+		"c.ts\n" +
+		"#EXT-X-DATERANGE:ID=\"cue-1\",START-DATE=\"2020-01-01T00:00:10.000Z\",DURATION=20\n" +
+		"# after in\n" +
+		"#EXTINF:10,\n" +
+		"d.ts\n"
+	if b.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
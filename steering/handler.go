@@ -0,0 +1,109 @@
+package steering
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// 客户端请求steering server时添加的查询参数
+const (
+	// 客户端正在使用的路径
+	QueryPathway = "_HLS_pathway"
+	// 客户端测量的吞吐量，bps
+	QueryThroughput = "_HLS_throughput"
+)
+
+// 客户端的请求
+type Request struct {
+	Pathway    string // 空表示客户端没有提供
+	Throughput int64  // 0表示客户端没有提供
+	HTTP       *http.Request
+}
+
+// steering server，可以并发使用。
+// Steer不是nil时，每个请求使用Steer返回的Manifest，否则都使用SetManifest设置的
+type Handler struct {
+	lock     sync.RWMutex
+	manifest *Manifest
+	// m是SetManifest设置的副本，可以修改后返回，返回nil表示使用m
+	Steer func(m *Manifest, r *Request) *Manifest
+}
+
+// 返回使用m的Handler
+func NewHandler(m *Manifest) *Handler {
+	h := new(Handler)
+	h.SetManifest(m)
+	return h
+}
+
+// 替换当前的Manifest，客户端在下一次请求时生效
+func (h *Handler) SetManifest(m *Manifest) {
+	h.lock.Lock()
+	h.manifest = m
+	h.lock.Unlock()
+}
+
+// 返回当前的Manifest
+func (h *Handler) Manifest() *Manifest {
+	h.lock.RLock()
+	m := h.manifest
+	h.lock.RUnlock()
+	return m
+}
+
+// 只支持GET，输出application/json
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	m := h.Manifest()
+	if m == nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	if h.Steer != nil {
+		q := r.URL.Query()
+		req := &Request{Pathway: q.Get(QueryPathway), HTTP: r}
+		req.Throughput, _ = strconv.ParseInt(q.Get(QueryThroughput), 10, 64)
+		if s := h.Steer(m.copy(), req); s != nil {
+			m = s
+		}
+	}
+	var b bytes.Buffer
+	_, err := m.WriteTo(&b)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(b.Len()))
+	// 客户端根据TTL重新请求，不需要缓存
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Method == http.MethodGet {
+		w.Write(b.Bytes())
+	}
+}
+
+// 返回m的副本，Steer可以修改PATHWAY-PRIORITY和PATHWAY-CLONES，
+// 但是URI-REPLACEMENT中的map是共享的，不能修改
+func (m *Manifest) copy() *Manifest {
+	c := *m
+	c.PATHWAY_PRIORITY = append([]string(nil), m.PATHWAY_PRIORITY...)
+	c.PATHWAY_CLONES = append([]PathwayClone(nil), m.PATHWAY_CLONES...)
+	return &c
+}
+
+// 把id移到PATHWAY-PRIORITY的最前面，id不在PATHWAY-PRIORITY中时添加
+func (m *Manifest) Prefer(id string) {
+	p := []string{id}
+	for _, s := range m.PATHWAY_PRIORITY {
+		if s != id {
+			p = append(p, s)
+		}
+	}
+	m.PATHWAY_PRIORITY = p
+}
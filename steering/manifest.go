@@ -0,0 +1,197 @@
+// content steering的steering manifest，draft-pantos-hls-rfc8216bis 7.1
+// 主列表的#EXT-X-CONTENT-STEERING指向steering server，
+// 客户端定时请求，根据PATHWAY-PRIORITY选择variant的PATHWAY-ID
+package steering
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	m3u8 "github.com/qq51529210/m3u8"
+)
+
+// 当前唯一的版本
+const Version = 1
+
+// 默认的TTL，秒
+const DefaultTTL = 300
+
+var (
+	// 不支持的VERSION
+	ErrVersion = errors.New("unsupported VERSION")
+	// 缺少TTL或者TTL不是正数
+	ErrTTL = errors.New("invalid TTL")
+	// PATHWAY-PRIORITY是空
+	ErrEmptyPriority = errors.New("empty PATHWAY-PRIORITY")
+)
+
+// steering manifest，JSON对象
+type Manifest struct {
+	VERSION          int            `json:"VERSION"`
+	TTL              int64          `json:"TTL"` // 秒
+	RELOAD_URI       string         `json:"RELOAD-URI,omitempty"`
+	PATHWAY_PRIORITY []string       `json:"PATHWAY-PRIORITY"`
+	PATHWAY_CLONES   []PathwayClone `json:"PATHWAY-CLONES,omitempty"`
+}
+
+// 从BASE-ID复制一个新的路径ID，使用URI-REPLACEMENT修改URI
+type PathwayClone struct {
+	BASE_ID         string         `json:"BASE-ID"`
+	ID              string         `json:"ID"`
+	URI_REPLACEMENT URIReplacement `json:"URI-REPLACEMENT"`
+}
+
+// 复制的路径修改URI的规则
+type URIReplacement struct {
+	HOST             string            `json:"HOST,omitempty"`
+	QUERY_PARAMETERS map[string]string `json:"QUERY-PARAMETERS,omitempty"`
+	// STABLE-VARIANT-ID:URI，优先于HOST和QUERY-PARAMETERS
+	PER_VARIANT_URIS map[string]string `json:"PER-VARIANT-URIS,omitempty"`
+	// STABLE-RENDITION-ID:URI，优先于HOST和QUERY-PARAMETERS
+	PER_RENDITION_URIS map[string]string `json:"PER-RENDITION-URIS,omitempty"`
+}
+
+// 返回VERSION是1的Manifest，ttl是0使用DefaultTTL
+func NewManifest(ttl int64, priority ...string) *Manifest {
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	return &Manifest{
+		VERSION:          Version,
+		TTL:              ttl,
+		PATHWAY_PRIORITY: priority,
+	}
+}
+
+// 返回使用p的所有路径的Manifest，#EXT-X-CONTENT-STEERING的PATHWAY-ID排在最前面
+func FromMasterPlayList(p *m3u8.MasterPlayList, ttl int64) *Manifest {
+	pathways := p.Pathways()
+	if p.EXT_X_CONTENT_STEERING != nil && p.EXT_X_CONTENT_STEERING.PATHWAY_ID != "" {
+		first := p.EXT_X_CONTENT_STEERING.PATHWAY_ID
+		priority := []string{first}
+		for _, id := range pathways {
+			if id != first {
+				priority = append(priority, id)
+			}
+		}
+		pathways = priority
+	}
+	return NewManifest(ttl, pathways...)
+}
+
+// 解析r中的JSON，并检查
+func Parse(r io.Reader) (*Manifest, error) {
+	m := new(Manifest)
+	err := json.NewDecoder(r).Decode(m)
+	if err != nil {
+		return nil, err
+	}
+	err = m.Check()
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// 检查VERSION，TTL，PATHWAY-PRIORITY和PATHWAY-CLONES
+func (m *Manifest) Check() error {
+	if m.VERSION != Version {
+		return fmt.Errorf("%w %d", ErrVersion, m.VERSION)
+	}
+	if m.TTL <= 0 {
+		return fmt.Errorf("%w %d", ErrTTL, m.TTL)
+	}
+	if len(m.PATHWAY_PRIORITY) < 1 {
+		return ErrEmptyPriority
+	}
+	ids := make(map[string]bool)
+	for _, id := range m.PATHWAY_PRIORITY {
+		if !m3u8.IsPathwayID(id) {
+			return fmt.Errorf("invalid PATHWAY-PRIORITY '%s'", id)
+		}
+		if ids[id] {
+			return fmt.Errorf("duplicate PATHWAY-PRIORITY '%s'", id)
+		}
+		ids[id] = true
+	}
+	// 复制的ID可以出现在PATHWAY-PRIORITY中，但是不能重复复制
+	clones := make(map[string]bool)
+	for i := 0; i < len(m.PATHWAY_CLONES); i++ {
+		c := &m.PATHWAY_CLONES[i]
+		if c.BASE_ID == "" {
+			return fmt.Errorf("PATHWAY-CLONES[%d] missing BASE-ID", i)
+		}
+		if !m3u8.IsPathwayID(c.BASE_ID) {
+			return fmt.Errorf("PATHWAY-CLONES[%d] invalid BASE-ID '%s'", i, c.BASE_ID)
+		}
+		if !m3u8.IsPathwayID(c.ID) {
+			return fmt.Errorf("PATHWAY-CLONES[%d] invalid ID '%s'", i, c.ID)
+		}
+		if clones[c.ID] || c.ID == c.BASE_ID {
+			return fmt.Errorf("PATHWAY-CLONES[%d] duplicate ID '%s'", i, c.ID)
+		}
+		clones[c.ID] = true
+	}
+	return nil
+}
+
+// 输出JSON
+func (m *Manifest) WriteTo(w io.Writer) (int64, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// 返回ID是id的PathwayClone，没有返回nil
+func (m *Manifest) Clone(id string) *PathwayClone {
+	for i := 0; i < len(m.PATHWAY_CLONES); i++ {
+		if m.PATHWAY_CLONES[i].ID == id {
+			return &m.PATHWAY_CLONES[i]
+		}
+	}
+	return nil
+}
+
+// 返回复制的variant的URI，stableID是STABLE-VARIANT-ID
+func (r *URIReplacement) VariantURI(uri, stableID string) (string, error) {
+	if s, ok := r.PER_VARIANT_URIS[stableID]; ok && stableID != "" {
+		return s, nil
+	}
+	return r.replace(uri)
+}
+
+// 返回复制的rendition的URI，stableID是STABLE-RENDITION-ID
+func (r *URIReplacement) RenditionURI(uri, stableID string) (string, error) {
+	if s, ok := r.PER_RENDITION_URIS[stableID]; ok && stableID != "" {
+		return s, nil
+	}
+	return r.replace(uri)
+}
+
+// 替换uri的host，添加QUERY-PARAMETERS，相对的uri不替换host
+func (r *URIReplacement) replace(uri string) (string, error) {
+	if r.HOST == "" && len(r.QUERY_PARAMETERS) < 1 {
+		return uri, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if r.HOST != "" && u.Host != "" {
+		u.Host = r.HOST
+	}
+	if len(r.QUERY_PARAMETERS) > 0 {
+		q := u.Query()
+		for k, v := range r.QUERY_PARAMETERS {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), nil
+}
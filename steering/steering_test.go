@@ -0,0 +1,253 @@
+package steering
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	m3u8 "github.com/qq51529210/m3u8"
+)
+
+func TestManifestCheck(t *testing.T) {
+	for _, c := range []struct {
+		m   Manifest
+		err error // nil表示只检查有没有错误
+		ok  bool
+	}{
+		{Manifest{VERSION: 1, TTL: 300, PATHWAY_PRIORITY: []string{"CDN-A", "CDN-B"}}, nil, true},
+		{Manifest{VERSION: 2, TTL: 300, PATHWAY_PRIORITY: []string{"CDN-A"}}, ErrVersion, false},
+		{Manifest{VERSION: 1, PATHWAY_PRIORITY: []string{"CDN-A"}}, ErrTTL, false},
+		{Manifest{VERSION: 1, TTL: -1, PATHWAY_PRIORITY: []string{"CDN-A"}}, ErrTTL, false},
+		{Manifest{VERSION: 1, TTL: 300}, ErrEmptyPriority, false},
+		{Manifest{VERSION: 1, TTL: 300, PATHWAY_PRIORITY: []string{""}}, nil, false},
+		{Manifest{VERSION: 1, TTL: 300, PATHWAY_PRIORITY: []string{"CDN A"}}, nil, false},
+		{Manifest{VERSION: 1, TTL: 300, PATHWAY_PRIORITY: []string{"CDN-A", "CDN-A"}}, nil, false},
+		// PATHWAY-CLONES
+		{Manifest{VERSION: 1, TTL: 300, PATHWAY_PRIORITY: []string{"CDN-A", "CDN-C"},
+			PATHWAY_CLONES: []PathwayClone{{BASE_ID: "CDN-A", ID: "CDN-C"}}}, nil, true},
+		{Manifest{VERSION: 1, TTL: 300, PATHWAY_PRIORITY: []string{"CDN-A"},
+			PATHWAY_CLONES: []PathwayClone{{ID: "CDN-C"}}}, nil, false},
+		{Manifest{VERSION: 1, TTL: 300, PATHWAY_PRIORITY: []string{"CDN-A"},
+			PATHWAY_CLONES: []PathwayClone{{BASE_ID: "CDN/A", ID: "CDN-C"}}}, nil, false},
+		{Manifest{VERSION: 1, TTL: 300, PATHWAY_PRIORITY: []string{"CDN-A"},
+			PATHWAY_CLONES: []PathwayClone{{BASE_ID: "CDN-A"}}}, nil, false},
+		{Manifest{VERSION: 1, TTL: 300, PATHWAY_PRIORITY: []string{"CDN-A"},
+			PATHWAY_CLONES: []PathwayClone{{BASE_ID: "CDN-A", ID: "CDN C"}}}, nil, false},
+		{Manifest{VERSION: 1, TTL: 300, PATHWAY_PRIORITY: []string{"CDN-A"},
+			PATHWAY_CLONES: []PathwayClone{{BASE_ID: "CDN-A", ID: "CDN-A"}}}, nil, false},
+		{Manifest{VERSION: 1, TTL: 300, PATHWAY_PRIORITY: []string{"CDN-A"},
+			PATHWAY_CLONES: []PathwayClone{{BASE_ID: "CDN-A", ID: "CDN-C"}, {BASE_ID: "CDN-A", ID: "CDN-C"}}}, nil, false},
+	} {
+		err := c.m.Check()
+		if c.ok != (err == nil) {
+			t.Fatalf("%+v: got %v", c.m, err)
+		}
+		if c.err != nil && !errors.Is(err, c.err) {
+			t.Fatalf("%+v: got %v, want %v", c.m, err, c.err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, c := range []struct {
+		src string
+		ok  bool
+	}{
+		{`{"VERSION":1,"TTL":300,"RELOAD-URI":"next.json","PATHWAY-PRIORITY":["CDN-A","CDN-B"],` +
+			`"PATHWAY-CLONES":[{"BASE-ID":"CDN-A","ID":"CDN-C","URI-REPLACEMENT":{"HOST":"c.example.com"}}]}`, true},
+		{`{"VERSION":1,"TTL":300,"PATHWAY-PRIORITY":[]}`, false},
+		{`{"VERSION":1,"TTL":300,"PATHWAY-PRIORITY":["CDN-A"]`, false},
+		{`[]`, false},
+	} {
+		m, err := Parse(strings.NewReader(c.src))
+		if c.ok != (err == nil) {
+			t.Fatalf("%s: got %v", c.src, err)
+		}
+		if !c.ok {
+			continue
+		}
+		// 输出以后再解析，应该一样
+		var b strings.Builder
+		if _, err := m.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		m2, err := Parse(strings.NewReader(b.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, m2) {
+			t.Fatalf("got %+v, want %+v", m2, m)
+		}
+		if m.RELOAD_URI != "next.json" || m.Clone("CDN-C").URI_REPLACEMENT.HOST != "c.example.com" || m.Clone("CDN-A") != nil {
+			t.Fatalf("got %+v", m)
+		}
+	}
+}
+
+func TestFromMasterPlayList(t *testing.T) {
+	for _, c := range []struct {
+		src  string
+		ttl  int64
+		want []string
+	}{
+		{
+			"#EXTM3U\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1000,PATHWAY-ID=\"CDN-A\"\n" +
+				"a.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1000\n" +
+				"b.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=2000,PATHWAY-ID=\"CDN-A\"\n" +
+				"c.m3u8\n",
+			0,
+			[]string{"CDN-A", m3u8.DefaultPathwayID},
+		},
+		{
+			"#EXTM3U\n" +
+				"#EXT-X-CONTENT-STEERING:SERVER-URI=\"/steering\",PATHWAY-ID=\"CDN-B\"\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1000,PATHWAY-ID=\"CDN-A\"\n" +
+				"a.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1000,PATHWAY-ID=\"CDN-B\"\n" +
+				"b.m3u8\n" +
+				"#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=100,URI=\"i.m3u8\",PATHWAY-ID=\"CDN-C\"\n",
+			60,
+			[]string{"CDN-B", "CDN-A", "CDN-C"},
+		},
+	} {
+		p, err := m3u8.ParseMasterPlayList(strings.NewReader(c.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := FromMasterPlayList(p, c.ttl)
+		if !reflect.DeepEqual(m.PATHWAY_PRIORITY, c.want) {
+			t.Fatalf("got %v, want %v", m.PATHWAY_PRIORITY, c.want)
+		}
+		ttl := c.ttl
+		if ttl == 0 {
+			ttl = DefaultTTL
+		}
+		if m.VERSION != Version || m.TTL != ttl {
+			t.Fatalf("got VERSION %d TTL %d", m.VERSION, m.TTL)
+		}
+		if err := m.Check(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestURIReplacement(t *testing.T) {
+	r := URIReplacement{
+		HOST:               "b.example.com",
+		QUERY_PARAMETERS:   map[string]string{"token": "x"},
+		PER_VARIANT_URIS:   map[string]string{"v1": "https://v.example.com/v1.m3u8"},
+		PER_RENDITION_URIS: map[string]string{"r1": "https://r.example.com/r1.m3u8"},
+	}
+	for _, c := range []struct {
+		uri, stableID string
+		rendition     bool
+		want          string
+	}{
+		{"https://a.example.com/v/a.m3u8?x=1", "", false, "https://b.example.com/v/a.m3u8?token=x&x=1"},
+		// 相对的URI不替换host
+		{"v/a.m3u8", "", false, "v/a.m3u8?token=x"},
+		// PER-VARIANT-URIS优先
+		{"https://a.example.com/v/a.m3u8", "v1", false, "https://v.example.com/v1.m3u8"},
+		{"https://a.example.com/v/a.m3u8", "v2", false, "https://b.example.com/v/a.m3u8?token=x"},
+		// PER-VARIANT-URIS和PER-RENDITION-URIS是分开的
+		{"https://a.example.com/r/a.m3u8", "v1", true, "https://b.example.com/r/a.m3u8?token=x"},
+		{"https://a.example.com/r/a.m3u8", "r1", true, "https://r.example.com/r1.m3u8"},
+	} {
+		var s string
+		var err error
+		if c.rendition {
+			s, err = r.RenditionURI(c.uri, c.stableID)
+		} else {
+			s, err = r.VariantURI(c.uri, c.stableID)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != c.want {
+			t.Fatalf("%s %s: got %s, want %s", c.uri, c.stableID, s, c.want)
+		}
+	}
+	// 空的URI-REPLACEMENT不修改
+	var empty URIReplacement
+	if s, err := empty.VariantURI("https://a.example.com/a.m3u8?x=1", ""); err != nil || s != "https://a.example.com/a.m3u8?x=1" {
+		t.Fatalf("got %s %v", s, err)
+	}
+	if _, err := r.VariantURI("%zz", ""); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(NewManifest(60, "CDN-A", "CDN-B"))
+	var req *Request
+	h.Steer = func(m *Manifest, r *Request) *Manifest {
+		req = r
+		// 吞吐量低的客户端使用CDN-B
+		if r.Throughput > 0 && r.Throughput < 1000000 {
+			m.Prefer("CDN-B")
+		}
+		return m
+	}
+	for _, c := range []struct {
+		query      string
+		pathway    string
+		throughput int64
+		want       []string
+	}{
+		{"", "", 0, []string{"CDN-A", "CDN-B"}},
+		{"?_HLS_pathway=CDN-A&_HLS_throughput=500000", "CDN-A", 500000, []string{"CDN-B", "CDN-A"}},
+		{"?_HLS_pathway=CDN-B&_HLS_throughput=5000000", "CDN-B", 5000000, []string{"CDN-A", "CDN-B"}},
+		{"?_HLS_throughput=x", "", 0, []string{"CDN-A", "CDN-B"}},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/steering"+c.query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d", c.query, w.Code)
+		}
+		if s := w.Header().Get("Content-Type"); s != "application/json" {
+			t.Fatalf("%s: got Content-Type %s", c.query, s)
+		}
+		if s := w.Header().Get("Content-Length"); s != strconv.Itoa(w.Body.Len()) {
+			t.Fatalf("%s: got Content-Length %s, body %d", c.query, s, w.Body.Len())
+		}
+		if req.Pathway != c.pathway || req.Throughput != c.throughput || req.HTTP == nil {
+			t.Fatalf("%s: got %+v", c.query, req)
+		}
+		var m Manifest
+		if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m.PATHWAY_PRIORITY, c.want) {
+			t.Fatalf("%s: got %v, want %v", c.query, m.PATHWAY_PRIORITY, c.want)
+		}
+	}
+	// Steer修改的是副本
+	if p := h.Manifest().PATHWAY_PRIORITY; !reflect.DeepEqual(p, []string{"CDN-A", "CDN-B"}) {
+		t.Fatalf("got %v", p)
+	}
+	// HEAD没有body
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/steering", nil))
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Length") == "0" {
+		t.Fatalf("HEAD: got status %d, body %d", w.Code, w.Body.Len())
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/steering", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD" {
+		t.Fatalf("POST: got status %d", w.Code)
+	}
+	h.SetManifest(nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/steering", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,59 @@
+package m3u8
+
+import "sync"
+
+// 自定义tag解析后的值，输出时调用Encode，Encode需要输出整行，包括tag的名称
+type Tag interface {
+	Encode(w *Writer) error
+}
+
+// 解析出来的自定义tag，Index是它在所在部分中的行号，见UnknownLine
+type CustomTag struct {
+	Index int
+	Tag   Tag
+}
+
+// 自定义tag的解析和输出，比如#EXT-X-CUE-OUT，#EXT-X-ASSET。
+// Decode解析ParseLine分出来的<value>，返回新的Tag，不修改自己。
+// value指向Reader的缓存，只在调用期间有效，Tag需要保存的话要复制，比如string(value)。
+// 一般是同一个类型实现，注册的是零值，比如RegisterTag("#EXT-X-CUE-OUT", new(CueOut))
+type TagHandler interface {
+	Tag
+	Decode(value []byte) (Tag, error)
+}
+
+// 自定义tag的注册表，key是tag的名称，比如#EXT-X-CUE-OUT，可以并发使用
+type TagRegistry struct {
+	lock     sync.RWMutex
+	handlers map[string]TagHandler
+}
+
+// 默认的注册表，Decoder默认使用
+var DefaultTagRegistry = new(TagRegistry)
+
+// 注册name的handler，替换已经注册的，handler是nil表示删除
+func (r *TagRegistry) Register(name string, handler TagHandler) {
+	r.lock.Lock()
+	if handler == nil {
+		delete(r.handlers, name)
+	} else {
+		if r.handlers == nil {
+			r.handlers = make(map[string]TagHandler)
+		}
+		r.handlers[name] = handler
+	}
+	r.lock.Unlock()
+}
+
+// 返回name的handler，没有注册返回nil
+func (r *TagRegistry) Lookup(name string) TagHandler {
+	r.lock.RLock()
+	h := r.handlers[name]
+	r.lock.RUnlock()
+	return h
+}
+
+// 在DefaultTagRegistry中注册name的handler
+func RegisterTag(name string, handler TagHandler) {
+	DefaultTagRegistry.Register(name, handler)
+}
@@ -0,0 +1,126 @@
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+// 测试用的#EXT-X-CUE-OUT
+type testCueOut struct {
+	value string
+}
+
+func (c *testCueOut) Decode(value []byte) (Tag, error) {
+	return &testCueOut{value: string(value)}, nil
+}
+
+func (c *testCueOut) Encode(w *Writer) error {
+	_, err := w.Tag("#EXT-X-CUE-OUT", c.value)
+	return err
+}
+
+// 返回tags的Index和值
+func testCustomTags(tags []CustomTag) ([]int, []string) {
+	var index []int
+	var value []string
+	for _, t := range tags {
+		index = append(index, t.Index)
+		value = append(value, t.Tag.(*testCueOut).value)
+	}
+	return index, value
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTagRegistry(t *testing.T) {
+	var r TagRegistry
+	if r.Lookup("#EXT-X-CUE-OUT") != nil {
+		t.Fatal("want nil")
+	}
+	r.Register("#EXT-X-CUE-OUT", new(testCueOut))
+	if r.Lookup("#EXT-X-CUE-OUT") == nil {
+		t.Fatal("want handler")
+	}
+	r.Register("#EXT-X-CUE-OUT", nil)
+	if r.Lookup("#EXT-X-CUE-OUT") != nil {
+		t.Fatal("want nil after Register(nil)")
+	}
+}
+
+// 自定义的tag解析到所在的部分，Index是在这部分中的行号，输出时放回原来的位置
+func TestCustomTagRoundTrip(t *testing.T) {
+	var r TagRegistry
+	r.Register("#EXT-X-CUE-OUT", new(testCueOut))
+	media := "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-CUE-OUT:10\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXTINF:10,\n" +
+		"a.ts\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2020-01-02T03:04:05.000Z\n" +
+		"# comment\n" +
+		"#EXT-X-CUE-OUT:20\n" +
+		"#EXTINF:10,\n" +
+		"b.ts\n" +
+		"#EXT-X-CUE-OUT:30\n" +
+		"#EXT-X-ENDLIST\n"
+	d := NewDecoder(strings.NewReader(media), Strict)
+	d.SetTagRegistry(&r)
+	p, err := d.DecodeMedia()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		tags  []CustomTag
+		index []int
+		value string
+	}{
+		// 列表开始部分，#EXT-X-VERSION后面
+		{p.Tags, []int{1}, "10"},
+		// 片段中，#EXT-X-PROGRAM-DATE-TIME和注释后面
+		{p.MediaSegment[1].Tags, []int{2}, "20"},
+		// 最后一个片段后面，#EXT-X-ENDLIST前面
+		{p.TrailingTags, []int{0}, "30"},
+	} {
+		index, value := testCustomTags(c.tags)
+		if !equalInts(index, c.index) || len(value) != 1 || value[0] != c.value {
+			t.Fatalf("got %v %v, want %v %s", index, value, c.index, c.value)
+		}
+	}
+	if len(p.MediaSegment[0].Tags) != 0 {
+		t.Fatalf("got %v", p.MediaSegment[0].Tags)
+	}
+	if s := encodeString(t, p); s != media {
+		t.Fatalf("got\n%s\nwant\n%s", s, media)
+	}
+
+	master := "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-CUE-OUT:10\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1\n" +
+		"a.m3u8\n" +
+		"#EXT-X-CUE-OUT:20\n"
+	d = NewDecoder(strings.NewReader(master), Strict)
+	d.SetTagRegistry(&r)
+	m, err := d.DecodeMaster()
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, value := testCustomTags(m.Tags)
+	if !equalInts(index, []int{1, 4}) || len(value) != 2 || value[0] != "10" || value[1] != "20" {
+		t.Fatalf("got %v %v", index, value)
+	}
+	if s := encodeString(t, m); s != master {
+		t.Fatalf("got\n%s\nwant\n%s", s, master)
+	}
+}
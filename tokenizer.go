@@ -0,0 +1,203 @@
+package m3u8
+
+import (
+	"bytes"
+	"io"
+)
+
+// 一行的类型
+type TokenKind int
+
+const (
+	// 空行
+	BlankToken TokenKind = iota
+	// #EXT开头的tag
+	TagToken
+	// 不是#EXT开头的注释
+	CommentToken
+	// URI
+	URIToken
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case BlankToken:
+		return "blank"
+	case TagToken:
+		return "tag"
+	case CommentToken:
+		return "comment"
+	case URIToken:
+		return "URI"
+	default:
+		return "unknown"
+	}
+}
+
+// tag的编号，比较编号不需要比较字符串
+type TagID int
+
+const (
+	// 不是RFC8216的tag，比如自定义的tag
+	TagID_UNKNOWN TagID = iota
+	// basic tags
+	TagID_EXTM3U
+	TagID_EXT_X_VERSION
+	// media segment tags
+	TagID_EXTINF
+	TagID_EXT_X_BYTERANGE
+	TagID_EXT_X_DISCONTINUITY
+	TagID_EXT_X_KEY
+	TagID_EXT_X_MAP
+	TagID_EXT_X_PROGRAM_DATE_TIME
+	TagID_EXT_X_DATERANGE
+	TagID_EXT_X_GAP
+	TagID_EXT_X_BITRATE
+	// media playlist tags
+	TagID_EXT_X_TARGETDURATION
+	TagID_EXT_X_MEDIA_SEQUENCE
+	TagID_EXT_X_DISCONTINUITY_SEQUENCE
+	TagID_EXT_X_ENDLIST
+	TagID_EXT_X_PLAYLIST_TYPE
+	TagID_EXT_X_I_FRAMES_ONLY
+	// master playlist tags
+	TagID_EXT_X_MEDIA
+	TagID_EXT_X_STREAM_INF
+	TagID_EXT_X_I_FRAME_STREAM_INF
+	TagID_EXT_X_SESSION_DATA
+	TagID_EXT_X_SESSION_KEY
+	TagID_EXT_X_INDEPENDENT_SEGMENTS
+	TagID_EXT_X_START
+	TagID_EXT_X_DEFINE
+	TagID_EXT_X_CONTENT_STEERING
+	// low-latency tags
+	TagID_EXT_X_PART
+	TagID_EXT_X_PART_INF
+	TagID_EXT_X_SERVER_CONTROL
+	TagID_EXT_X_PRELOAD_HINT
+	TagID_EXT_X_RENDITION_REPORT
+	TagID_EXT_X_SKIP
+	tagIDCount
+)
+
+// TagID对应的名称
+var tagNames = [tagIDCount]string{
+	TagID_EXTM3U:                       TagEXTM3U,
+	TagID_EXT_X_VERSION:                TagEXT_X_VERSION,
+	TagID_EXTINF:                       TagEXTINF,
+	TagID_EXT_X_BYTERANGE:              TagEXT_X_BYTERANGE,
+	TagID_EXT_X_DISCONTINUITY:          TagEXT_X_DISCONTINUITY,
+	TagID_EXT_X_KEY:                    TagEXT_X_KEY,
+	TagID_EXT_X_MAP:                    TagEXT_X_MAP,
+	TagID_EXT_X_PROGRAM_DATE_TIME:      TagEXT_X_PROGRAM_DATE_TIME,
+	TagID_EXT_X_DATERANGE:              TagEXT_X_DATERANGE,
+	TagID_EXT_X_GAP:                    TagEXT_X_GAP,
+	TagID_EXT_X_BITRATE:                TagEXT_X_BITRATE,
+	TagID_EXT_X_TARGETDURATION:         TagEXT_X_TARGETDURATION,
+	TagID_EXT_X_MEDIA_SEQUENCE:         TagEXT_X_MEDIA_SEQUENCE,
+	TagID_EXT_X_DISCONTINUITY_SEQUENCE: TagEXT_X_DISCONTINUITY_SEQUENCE,
+	TagID_EXT_X_ENDLIST:                TagEXT_X_ENDLIST,
+	TagID_EXT_X_PLAYLIST_TYPE:          TagEXT_X_PLAYLIST_TYPE,
+	TagID_EXT_X_I_FRAMES_ONLY:          TagEXT_X_I_FRAMES_ONLY,
+	TagID_EXT_X_MEDIA:                  TagEXT_X_MEDIA,
+	TagID_EXT_X_STREAM_INF:             TagEXT_X_STREAM_INF,
+	TagID_EXT_X_I_FRAME_STREAM_INF:     TagEXT_X_I_FRAME_STREAM_INF,
+	TagID_EXT_X_SESSION_DATA:           TagEXT_X_SESSION_DATA,
+	TagID_EXT_X_SESSION_KEY:            TagEXT_X_SESSION_KEY,
+	TagID_EXT_X_INDEPENDENT_SEGMENTS:   TagEXT_X_INDEPENDENT_SEGMENTS,
+	TagID_EXT_X_START:                  TagEXT_X_START,
+	TagID_EXT_X_DEFINE:                 TagEXT_X_DEFINE,
+	TagID_EXT_X_CONTENT_STEERING:       TagEXT_X_CONTENT_STEERING,
+	TagID_EXT_X_PART:                   TagEXT_X_PART,
+	TagID_EXT_X_PART_INF:               TagEXT_X_PART_INF,
+	TagID_EXT_X_SERVER_CONTROL:         TagEXT_X_SERVER_CONTROL,
+	TagID_EXT_X_PRELOAD_HINT:           TagEXT_X_PRELOAD_HINT,
+	TagID_EXT_X_RENDITION_REPORT:       TagEXT_X_RENDITION_REPORT,
+	TagID_EXT_X_SKIP:                   TagEXT_X_SKIP,
+}
+
+// 名称:TagID
+var tagIDs = func() map[string]TagID {
+	m := make(map[string]TagID, len(tagNames))
+	for i, s := range tagNames {
+		if s != "" {
+			m[s] = TagID(i)
+		}
+	}
+	return m
+}()
+
+// 返回tag的名称，比如#EXTINF，TagID_UNKNOWN返回空
+func (id TagID) String() string {
+	if id <= TagID_UNKNOWN || id >= tagIDCount {
+		return ""
+	}
+	return tagNames[id]
+}
+
+// 返回名称是name的TagID，name包括#，比如#EXTINF，不认识的返回TagID_UNKNOWN
+func LookupTagID(name []byte) TagID {
+	// map[string(b)]不会分配内存
+	return tagIDs[string(name)]
+}
+
+// Tokenizer解析出来的一行，所有的[]byte在下一次调用Next之前有效，外部需要拷贝
+type Token struct {
+	Kind  TokenKind
+	Tag   TagID  // TagToken的编号
+	Name  []byte // TagToken的名称，包括#，比如#EXTINF
+	Value []byte // TagToken是':'后面的<value>，CommentToken是'#'后面的内容，URIToken是URI
+	Line  []byte // 整行，没有换行符
+}
+
+// 把Reader读取的行分类，不分配内存，不检查tag的值
+type Tokenizer struct {
+	reader *Reader
+	token  Token
+}
+
+// 创建Tokenizer，r是数据源，b是Reader的缓存，可以是nil
+func NewTokenizer(r io.Reader, b []byte) *Tokenizer {
+	return &Tokenizer{reader: NewReader(r, b)}
+}
+
+// 重新设置数据源r，可以重用Tokenizer
+func (t *Tokenizer) Reset(r io.Reader) {
+	t.reader.SetReader(r)
+	t.reader.pIdx = 0
+	t.reader.dIdx = 0
+	t.reader.dLen = 0
+}
+
+// 返回最后一次Next读取的行号，从1开始
+func (t *Tokenizer) Line() int {
+	return t.reader.Line()
+}
+
+// 读取下一行，没有数据返回io.EOF，返回的Token在下一次调用Next之前有效
+func (t *Tokenizer) Next() (*Token, error) {
+	line, err := t.reader.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	tk := &t.token
+	tk.Line = line
+	tk.Tag = TagID_UNKNOWN
+	tk.Name = nil
+	switch {
+	case len(line) == 0:
+		tk.Kind = BlankToken
+		tk.Value = line
+	case bytes.HasPrefix(line, tagEXT):
+		tk.Kind = TagToken
+		tk.Name, tk.Value = ParseLine(line)
+		tk.Tag = LookupTagID(tk.Name)
+	case line[0] == '#':
+		tk.Kind = CommentToken
+		tk.Value = line[1:]
+	default:
+		tk.Kind = URIToken
+		tk.Value = line
+	}
+	return tk, nil
+}
@@ -0,0 +1,152 @@
+package m3u8
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTokenizer(t *testing.T) {
+	src := "#EXTM3U\n#EXT-X-TARGETDURATION:10\n\n# comment\n#EXT-X-CUE-OUT:30\n#EXTINF:10,\na.ts\n#EXT-X-ENDLIST"
+	want := []struct {
+		kind  TokenKind
+		tag   TagID
+		name  string
+		value string
+	}{
+		{TagToken, TagID_EXTM3U, "#EXTM3U", ""},
+		{TagToken, TagID_EXT_X_TARGETDURATION, "#EXT-X-TARGETDURATION", "10"},
+		{BlankToken, TagID_UNKNOWN, "", ""},
+		{CommentToken, TagID_UNKNOWN, "", " comment"},
+		{TagToken, TagID_UNKNOWN, "#EXT-X-CUE-OUT", "30"},
+		{TagToken, TagID_EXTINF, "#EXTINF", "10,"},
+		{URIToken, TagID_UNKNOWN, "", "a.ts"},
+		{TagToken, TagID_EXT_X_ENDLIST, "#EXT-X-ENDLIST", ""},
+	}
+	tk := NewTokenizer(strings.NewReader(src), nil)
+	for i, w := range want {
+		token, err := tk.Next()
+		if err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if token.Kind != w.kind || token.Tag != w.tag || string(token.Name) != w.name || string(token.Value) != w.value {
+			t.Fatalf("line %d: got %v %v %q %q, want %v %v %q %q", i+1,
+				token.Kind, token.Tag, token.Name, token.Value, w.kind, w.tag, w.name, w.value)
+		}
+		if tk.Line() != i+1 {
+			t.Fatalf("Line() got %d, want %d", tk.Line(), i+1)
+		}
+	}
+	if _, err := tk.Next(); err != io.EOF {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+}
+
+func TestLookupTagID(t *testing.T) {
+	for id := TagID_UNKNOWN + 1; id < tagIDCount; id++ {
+		if LookupTagID([]byte(id.String())) != id {
+			t.Fatalf("%s: got %v", id, LookupTagID([]byte(id.String())))
+		}
+	}
+	if id := LookupTagID([]byte("#EXT-X-CUE-OUT")); id != TagID_UNKNOWN || id.String() != "" {
+		t.Fatalf("got %v", id)
+	}
+}
+
+// Reader的缓存够大以后，Next不分配内存
+func TestTokenizerAllocs(t *testing.T) {
+	data := testLargeMediaPlayList(100)
+	r := bytes.NewReader(data)
+	tk := NewTokenizer(r, make([]byte, 4096))
+	n := testing.AllocsPerRun(10, func() {
+		r.Reset(data)
+		tk.Reset(r)
+		for {
+			_, err := tk.Next()
+			if err != nil {
+				break
+			}
+		}
+	})
+	if n != 0 {
+		t.Fatalf("got %v allocs", n)
+	}
+}
+
+// 生成有n个片段的媒体列表
+func testLargeMediaPlayList(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:0\n")
+	b.WriteString("#EXT-X-KEY:METHOD=AES-128,URI=\"https://example.com/key\",IV=0x0123456789abcdef0123456789abcdef\n")
+	b.WriteString("#EXT-X-MAP:URI=\"init.mp4\"\n")
+	b.WriteString("#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00.000Z\n")
+	for i := 0; i < n; i++ {
+		if i%100 == 99 {
+			fmt.Fprintf(&b, "#EXT-X-DATERANGE:ID=\"ad-%d\",START-DATE=\"2020-01-01T00:00:00.000Z\",DURATION=30\n", i)
+		}
+		fmt.Fprintf(&b, "#EXTINF:5.005,\nhttps://media.example.com/segment-%d.m4s\n", i)
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.Bytes()
+}
+
+func BenchmarkTokenizer(b *testing.B) {
+	data := testLargeMediaPlayList(10000)
+	r := bytes.NewReader(data)
+	tk := NewTokenizer(r, make([]byte, 4096))
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		tk.Reset(r)
+		for {
+			_, err := tk.Next()
+			if err != nil {
+				if err != io.EOF {
+					b.Fatal(err)
+				}
+				break
+			}
+		}
+	}
+}
+
+// 只处理事件的基准，对比Tokenizer
+type benchmarkHandler struct{}
+
+func (h benchmarkHandler) OnHeader(p *MediaPlayList) error { return nil }
+
+func (h benchmarkHandler) OnDateRange(r *EXT_X_DATERANGE) error { return nil }
+
+func (h benchmarkHandler) OnSegment(s *MediaSegment) error { return nil }
+
+func (h benchmarkHandler) OnEnd(p *MediaPlayList) error { return nil }
+
+func BenchmarkScanner(b *testing.B) {
+	data := testLargeMediaPlayList(10000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := NewScanner(bytes.NewReader(data), Strict).Scan(benchmarkHandler{})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := testLargeMediaPlayList(10000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := ParseMediaPlayList(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,673 @@
+// 检查列表是否符合RFC8216的规则，主要是6.2服务端的规则
+// https://tools.ietf.org/html/rfc8216#section-6.2
+package validator
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	m3u8 "github.com/qq51529210/m3u8"
+)
+
+// 问题的严重程度
+type Severity int
+
+const (
+	// 违反了MUST的规则，客户端可能不能播放
+	Error Severity = iota + 1
+	// 违反了SHOULD的规则
+	Warning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// 检查出来的一个问题
+type Finding struct {
+	Severity Severity
+	Line     int    // 解析时的行号，0表示不知道
+	Tag      string // 相关的tag，可能是空
+	Message  string
+}
+
+func (f Finding) String() string {
+	s := f.Severity.String()
+	if f.Line > 0 {
+		s += fmt.Sprintf(" line %d", f.Line)
+	}
+	s += ": "
+	if f.Tag != "" {
+		s += f.Tag + " "
+	}
+	return s + f.Message
+}
+
+// 是否有Error级别的问题
+func HasError(findings []Finding) bool {
+	for i := 0; i < len(findings); i++ {
+		if findings[i].Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// 检查列表，p是*m3u8.MediaPlayList或者*m3u8.MasterPlayList
+func Validate(p m3u8.PlayList) []Finding {
+	switch l := p.(type) {
+	case *m3u8.MediaPlayList:
+		return ValidateMedia(l)
+	case *m3u8.MasterPlayList:
+		return ValidateMaster(l)
+	default:
+		return []Finding{{Severity: Error, Message: fmt.Sprintf("unknown playlist %T", p)}}
+	}
+}
+
+// 检查的状态
+type validator struct {
+	findings    []Finding
+	version     int64 // 列表的EXT-X-VERSION，没有是1
+	versionLine int   // #EXT-X-VERSION的行号
+}
+
+func (v *validator) add(severity Severity, line int, tag, format string, args ...interface{}) {
+	v.findings = append(v.findings, Finding{
+		Severity: severity,
+		Line:     line,
+		Tag:      tag,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// 检查EXT-X-VERSION是否满足列表中使用的功能，RFC8216 7
+func (v *validator) validateVersion(requirements []m3u8.VersionRequirement) {
+	for _, r := range requirements {
+		if v.version >= r.Version {
+			continue
+		}
+		feature := r.Feature
+		if feature != "" {
+			feature += " "
+		}
+		// 不知道功能的位置，使用#EXT-X-VERSION的行号
+		line := r.Line
+		if line == 0 {
+			line = v.versionLine
+		}
+		v.add(Error, line, r.Tag, "%srequires %s %d, but it is %d", feature, m3u8.TagEXT_X_VERSION, r.Version, v.version)
+	}
+}
+
+// 列表的EXT-X-VERSION，没有是1
+func playListVersion(n int64) int64 {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// 检查媒体列表
+func ValidateMedia(p *m3u8.MediaPlayList) []Finding {
+	v := &validator{version: playListVersion(p.EXT_X_VERSION), versionLine: p.Lines[m3u8.TagEXT_X_VERSION]}
+	// RFC8216 4.3.3.1
+	if p.EXT_X_TARGETDURATION < 1 && len(p.MediaSegment) > 0 {
+		v.add(Error, p.Lines[m3u8.TagEXT_X_TARGETDURATION], m3u8.TagEXT_X_TARGETDURATION, "missing or zero")
+	}
+	// RFC8216 4.3.3.5
+	if p.EXT_X_PLAYLIST_TYPE != "" && p.EXT_X_PLAYLIST_TYPE != "EVENT" && p.EXT_X_PLAYLIST_TYPE != "VOD" {
+		v.add(Error, p.Lines[m3u8.TagEXT_X_PLAYLIST_TYPE], m3u8.TagEXT_X_PLAYLIST_TYPE, "invalid enumerated-string '%s'", p.EXT_X_PLAYLIST_TYPE)
+	}
+	v.validateVersion(p.VersionRequirements())
+	var keys []*m3u8.EXT_X_KEY
+	var xmap *m3u8.EXT_X_MAP
+	programDateTime := false
+	dateRanges := make(map[string]*m3u8.EXT_X_DATERANGE)
+	// 第一个#EXT-X-DATERANGE的行号
+	dateRangeLine := 0
+	for i := 0; i < len(p.MediaSegment); i++ {
+		s := &p.MediaSegment[i]
+		if s.URI == "" {
+			v.add(Error, s.Line, "", "segment %d missing <URI>", i)
+		}
+		v.validateEXTINF(p, s)
+		if s.EXT_X_BYTERANGE != nil {
+			// RFC8216 4.3.2.2
+			if s.EXT_X_BYTERANGE.O == nil && (i == 0 || p.MediaSegment[i-1].EXT_X_BYTERANGE == nil ||
+				p.MediaSegment[i-1].URI != s.URI) {
+				v.add(Error, s.Line, m3u8.TagEXT_X_BYTERANGE, "<o> is not present, but previous segment is not a sub-range of the same resource")
+			}
+		}
+		// 同一个KEY和MAP只检查一次
+		v.validateKeys(keys, s.EXT_X_KEY)
+		keys = s.EXT_X_KEY
+		if s.EXT_X_MAP != nil && s.EXT_X_MAP != xmap {
+			v.validateEXT_X_MAP(s.EXT_X_MAP)
+		}
+		xmap = s.EXT_X_MAP
+		if !s.EXT_X_PROGRAM_DATE_TIME.IsZero() {
+			programDateTime = true
+		}
+		for j := 0; j < len(s.EXT_X_DATERANGE); j++ {
+			if len(dateRanges) == 0 {
+				dateRangeLine = s.EXT_X_DATERANGE[j].Line
+			}
+			v.validateEXT_X_DATERANGE(&s.EXT_X_DATERANGE[j], dateRanges)
+		}
+	}
+	// RFC8216 4.3.2.7
+	if len(dateRanges) > 0 && !programDateTime {
+		v.add(Error, dateRangeLine, m3u8.TagEXT_X_DATERANGE, "requires at least one %s", m3u8.TagEXT_X_PROGRAM_DATE_TIME)
+	}
+	v.validateEXT_X_START(p.EXT_X_START)
+	v.validateLowLatency(p)
+	return v.findings
+}
+
+// low-latency tags，draft-pantos-hls-rfc8216bis 4.4.3，4.4.4
+func (v *validator) validateLowLatency(p *m3u8.MediaPlayList) {
+	var partTarget float64
+	if p.EXT_X_PART_INF != nil {
+		partTarget = p.EXT_X_PART_INF.PART_TARGET
+		if partTarget <= 0 {
+			v.add(Error, p.EXT_X_PART_INF.Line, m3u8.TagEXT_X_PART_INF, "PART-TARGET must be positive")
+		}
+	}
+	validatePart := func(part *m3u8.EXT_X_PART) {
+		if partTarget == 0 {
+			if p.EXT_X_PART_INF == nil {
+				v.add(Error, part.Line, m3u8.TagEXT_X_PART, "requires %s", m3u8.TagEXT_X_PART_INF)
+				partTarget = -1
+			}
+			return
+		}
+		if part.DURATION > partTarget {
+			v.add(Error, part.Line, m3u8.TagEXT_X_PART, "DURATION %v is greater than PART-TARGET %v", part.DURATION, partTarget)
+		}
+	}
+	for i := 0; i < len(p.MediaSegment); i++ {
+		s := &p.MediaSegment[i]
+		for j := 0; j < len(s.EXT_X_PART); j++ {
+			validatePart(&s.EXT_X_PART[j])
+		}
+	}
+	for i := 0; i < len(p.EXT_X_PART); i++ {
+		validatePart(&p.EXT_X_PART[i])
+	}
+	if c := p.EXT_X_SERVER_CONTROL; c != nil {
+		tag := m3u8.TagEXT_X_SERVER_CONTROL
+		target := float64(p.EXT_X_TARGETDURATION)
+		if c.CAN_SKIP_UNTIL > 0 && c.CAN_SKIP_UNTIL < 6*target {
+			v.add(Error, c.Line, tag, "CAN-SKIP-UNTIL must be at least six times %s", m3u8.TagEXT_X_TARGETDURATION)
+		}
+		if c.HOLD_BACK > 0 && c.HOLD_BACK < 3*target {
+			v.add(Error, c.Line, tag, "HOLD-BACK must be at least three times %s", m3u8.TagEXT_X_TARGETDURATION)
+		}
+		if c.PART_HOLD_BACK > 0 && partTarget > 0 && c.PART_HOLD_BACK < 2*partTarget {
+			v.add(Error, c.Line, tag, "PART-HOLD-BACK must be at least twice PART-TARGET")
+		}
+		if c.CAN_SKIP_DATERANGES && c.CAN_SKIP_UNTIL <= 0 {
+			v.add(Error, c.Line, tag, "CAN-SKIP-DATERANGES requires CAN-SKIP-UNTIL")
+		}
+	}
+	if p.EXT_X_PART_INF != nil && (p.EXT_X_SERVER_CONTROL == nil || p.EXT_X_SERVER_CONTROL.PART_HOLD_BACK <= 0) {
+		line := p.EXT_X_PART_INF.Line
+		if p.EXT_X_SERVER_CONTROL != nil {
+			line = p.EXT_X_SERVER_CONTROL.Line
+		}
+		v.add(Error, line, m3u8.TagEXT_X_SERVER_CONTROL, "PART-HOLD-BACK is required when %s is present", m3u8.TagEXT_X_PART_INF)
+	}
+	// 每种TYPE最多一个
+	types := make(map[string]bool)
+	for i := 0; i < len(p.EXT_X_PRELOAD_HINT); i++ {
+		h := &p.EXT_X_PRELOAD_HINT[i]
+		if types[h.TYPE] {
+			v.add(Error, h.Line, m3u8.TagEXT_X_PRELOAD_HINT, "more than one TYPE=%s", h.TYPE)
+		}
+		types[h.TYPE] = true
+	}
+	if p.EXT_X_SKIP != nil && (p.EXT_X_SERVER_CONTROL == nil || p.EXT_X_SERVER_CONTROL.CAN_SKIP_UNTIL <= 0) {
+		v.add(Warning, p.EXT_X_SKIP.Line, m3u8.TagEXT_X_SKIP, "server does not advertise CAN-SKIP-UNTIL")
+	}
+}
+
+// RFC8216 4.3.2.1，4.3.3.1
+func (v *validator) validateEXTINF(p *m3u8.MediaPlayList, s *m3u8.MediaSegment) {
+	d := s.EXTINF.DURATION
+	if d < 0 || math.IsNaN(d) || math.IsInf(d, 0) {
+		v.add(Error, s.Line, m3u8.TagEXTINF, "invalid duration %v", d)
+		return
+	}
+	if p.EXT_X_TARGETDURATION > 0 && int64(math.Round(d)) > p.EXT_X_TARGETDURATION {
+		v.add(Error, s.Line, m3u8.TagEXTINF, "duration %v rounded is greater than %s %d",
+			d, m3u8.TagEXT_X_TARGETDURATION, p.EXT_X_TARGETDURATION)
+	}
+}
+
+// RFC8216 4.3.2.4，4.3.4.5
+// 检查片段的#EXT-X-KEY，prev是前一个片段的，已经检查过了
+func (v *validator) validateKeys(prev, keys []*m3u8.EXT_X_KEY) {
+	for i, k := range keys {
+		if hasKey(prev, k) {
+			continue
+		}
+		v.validateEXT_X_KEY(k, m3u8.TagEXT_X_KEY)
+		// 每个KEYFORMAT只能有一个
+		for _, k0 := range keys[:i] {
+			if k0.KeyFormat() == k.KeyFormat() {
+				v.add(Error, k.Line, m3u8.TagEXT_X_KEY, "more than one KEYFORMAT '%s' in a segment", k.KeyFormat())
+				break
+			}
+		}
+	}
+}
+
+// 返回keys中是否有key这个指针
+func hasKey(keys []*m3u8.EXT_X_KEY, key *m3u8.EXT_X_KEY) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) validateEXT_X_KEY(k *m3u8.EXT_X_KEY, tag string) {
+	switch k.METHOD {
+	case "NONE":
+		if tag == m3u8.TagEXT_X_SESSION_KEY {
+			v.add(Error, k.Line, tag, "METHOD must not be NONE")
+		} else if k.URI != "" || len(k.IV) > 0 || k.KEYFORMAT != "" || k.KEYFORMATVERSIONS != "" {
+			v.add(Error, k.Line, tag, "other attributes must not be present when METHOD is NONE")
+		}
+		return
+	case "AES-128", "SAMPLE-AES", "SAMPLE-AES-CTR":
+	default:
+		v.add(Error, k.Line, tag, "invalid METHOD '%s'", k.METHOD)
+	}
+	if k.URI == "" {
+		v.add(Error, k.Line, tag, "missing URI")
+	}
+	if len(k.IV) > 0 && len(k.IV) != 16 {
+		v.add(Error, k.Line, tag, "IV must be 128 bits")
+	}
+}
+
+// RFC8216 4.3.2.5
+func (v *validator) validateEXT_X_MAP(m *m3u8.EXT_X_MAP) {
+	if m.URI == "" {
+		v.add(Error, m.Line, m3u8.TagEXT_X_MAP, "missing URI")
+	}
+}
+
+// RFC8216 4.3.2.7，ids保存已经出现过的ID
+func (v *validator) validateEXT_X_DATERANGE(r *m3u8.EXT_X_DATERANGE, ids map[string]*m3u8.EXT_X_DATERANGE) {
+	tag := m3u8.TagEXT_X_DATERANGE
+	for _, err := range r.Check() {
+		v.add(Error, r.Line, tag, "%v", err)
+	}
+	if r.ID != "" {
+		if r0, ok := ids[r.ID]; ok {
+			// 同一个ID的tag，都有的属性的值必须一样，RFC8216 4.3.2.7
+			if name := conflictAttribute(r0, r); name != "" {
+				v.add(Error, r.Line, tag, "ID '%s' has a different %s", r.ID, name)
+			}
+		} else {
+			ids[r.ID] = r
+		}
+	}
+	v.validateCue(r)
+	if r.IsInterstitial() {
+		v.validateInterstitial(r)
+	}
+}
+
+// draft-pantos-hls-rfc8216bis 4.4.5.1
+func (v *validator) validateCue(r *m3u8.EXT_X_DATERANGE) {
+	cues := make(map[string]bool)
+	for _, c := range r.Cues() {
+		switch c {
+		case m3u8.CuePre, m3u8.CuePost, m3u8.CueOnce:
+		default:
+			v.add(Error, r.Line, m3u8.TagEXT_X_DATERANGE, "invalid CUE '%s', must be PRE|POST|ONCE", c)
+		}
+		cues[c] = true
+	}
+	if cues[m3u8.CuePre] && cues[m3u8.CuePost] {
+		v.add(Error, r.Line, m3u8.TagEXT_X_DATERANGE, "CUE must not contain both PRE and POST")
+	}
+}
+
+// draft-pantos-hls-rfc8216bis Appendix D.2
+func (v *validator) validateInterstitial(r *m3u8.EXT_X_DATERANGE) {
+	tag := m3u8.TagEXT_X_DATERANGE
+	i, err := r.Interstitial()
+	if err != nil {
+		v.add(Error, r.Line, tag, "%v", err)
+		return
+	}
+	if (i.X_ASSET_URI == "") == (i.X_ASSET_LIST == "") {
+		v.add(Error, r.Line, tag, "interstitial must contain either X-ASSET-URI or X-ASSET-LIST")
+	}
+	if i.X_PLAYOUT_LIMIT != nil && *i.X_PLAYOUT_LIMIT <= 0 {
+		v.add(Error, r.Line, tag, "X-PLAYOUT-LIMIT must be positive")
+	}
+	for _, s := range i.X_SNAP {
+		if s != "OUT" && s != "IN" {
+			v.add(Error, r.Line, tag, "invalid X-SNAP '%s', must be OUT|IN", s)
+		}
+	}
+	for _, s := range i.X_RESTRICT {
+		if s != "SKIP" && s != "JUMP" {
+			v.add(Error, r.Line, tag, "invalid X-RESTRICT '%s', must be SKIP|JUMP", s)
+		}
+	}
+}
+
+// 返回r1和r2都有，但是值不一样的属性
+func conflictAttribute(r1, r2 *m3u8.EXT_X_DATERANGE) string {
+	if r1.CLASS != "" && r2.CLASS != "" && r1.CLASS != r2.CLASS {
+		return "CLASS"
+	}
+	if r1.CUE != "" && r2.CUE != "" && r1.CUE != r2.CUE {
+		return "CUE"
+	}
+	if !r1.START_DATE.IsZero() && !r2.START_DATE.IsZero() && !r1.START_DATE.Equal(r2.START_DATE) {
+		return "START-DATE"
+	}
+	if !r1.END_DATE.IsZero() && !r2.END_DATE.IsZero() && !r1.END_DATE.Equal(r2.END_DATE) {
+		return "END-DATE"
+	}
+	if r1.DURATION != nil && r2.DURATION != nil && *r1.DURATION != *r2.DURATION {
+		return "DURATION"
+	}
+	if r1.PLANNED_DURATION != nil && r2.PLANNED_DURATION != nil && *r1.PLANNED_DURATION != *r2.PLANNED_DURATION {
+		return "PLANNED-DURATION"
+	}
+	if len(r1.SCTE35_CMD) > 0 && len(r2.SCTE35_CMD) > 0 && !bytes.Equal(r1.SCTE35_CMD, r2.SCTE35_CMD) {
+		return "SCTE35-CMD"
+	}
+	if len(r1.SCTE35_OUT) > 0 && len(r2.SCTE35_OUT) > 0 && !bytes.Equal(r1.SCTE35_OUT, r2.SCTE35_OUT) {
+		return "SCTE35-OUT"
+	}
+	if len(r1.SCTE35_IN) > 0 && len(r2.SCTE35_IN) > 0 && !bytes.Equal(r1.SCTE35_IN, r2.SCTE35_IN) {
+		return "SCTE35-IN"
+	}
+	for _, a := range r1.X_CLIENT_ATTRIBUTE {
+		i := r2.X_CLIENT_ATTRIBUTE.Index(a.Name)
+		if i >= 0 && r2.X_CLIENT_ATTRIBUTE[i] != a {
+			return a.Name
+		}
+	}
+	return ""
+}
+
+// RFC8216 4.3.5.2
+func (v *validator) validateEXT_X_START(s *m3u8.EXT_X_START) {
+	if s == nil {
+		return
+	}
+	if math.IsNaN(s.TIME_OFFSET) || math.IsInf(s.TIME_OFFSET, 0) {
+		v.add(Error, s.Line, m3u8.TagEXT_X_START, "invalid TIME-OFFSET")
+	}
+}
+
+// 检查主列表
+func ValidateMaster(p *m3u8.MasterPlayList) []Finding {
+	v := &validator{version: playListVersion(p.EXT_X_VERSION), versionLine: p.Lines[m3u8.TagEXT_X_VERSION]}
+	v.validateVersion(p.VersionRequirements())
+	// TYPE:GROUP-ID
+	groups := make(map[string][]*m3u8.EXT_X_MEDIA)
+	var groupKeys []string
+	for i := 0; i < len(p.EXT_X_MEDIA); i++ {
+		m := &p.EXT_X_MEDIA[i]
+		v.validateEXT_X_MEDIA(m)
+		k := m.TYPE + ":" + m.GROUP_ID
+		if _, ok := groups[k]; !ok {
+			groupKeys = append(groupKeys, k)
+		}
+		groups[k] = append(groups[k], m)
+	}
+	for _, k := range groupKeys {
+		v.validateGroup(groups[k])
+	}
+	// 有SCORE的variant的数量，第一个没有SCORE的variant的行号
+	scores, noScore := 0, 0
+	for i := 0; i < len(p.EXT_X_STREAM_INF); i++ {
+		s := &p.EXT_X_STREAM_INF[i]
+		tag := m3u8.TagEXT_X_STREAM_INF
+		v.validateBandwidth(s.Line, tag, s.BANDWIDTH, s.AVERAGE_BANDWIDTH)
+		if s.URI == "" {
+			v.add(Error, s.Line, tag, "missing <URI>")
+		}
+		// RFC8216 4.3.4.2
+		if s.CODECS == "" {
+			v.add(Warning, s.Line, tag, "should include CODECS")
+		}
+		if s.FRAME_RATE < 0 {
+			v.add(Error, s.Line, tag, "FRAME-RATE must not be negative")
+		}
+		v.validateVariant(s.Line, tag, s.SCORE, s.VIDEO_RANGE, s.REQ_VIDEO_LAYOUT, s.STABLE_VARIANT_ID)
+		if s.SCORE > 0 {
+			scores++
+		} else if noScore == 0 {
+			noScore = s.Line
+		}
+		v.validateGroupReference(groups, s.Line, tag, "AUDIO", s.AUDIO)
+		v.validateGroupReference(groups, s.Line, tag, "VIDEO", s.VIDEO)
+		v.validateGroupReference(groups, s.Line, tag, "SUBTITLES", s.SUBTITLES)
+		if s.CLOSED_CAPTIONS != "NONE" {
+			v.validateGroupReference(groups, s.Line, tag, "CLOSED-CAPTIONS", s.CLOSED_CAPTIONS)
+		}
+	}
+	// draft-pantos-hls-rfc8216bis 4.4.6.2
+	if scores > 0 && scores < len(p.EXT_X_STREAM_INF) {
+		v.add(Warning, noScore, m3u8.TagEXT_X_STREAM_INF, "SCORE should be present on every variant if it is present on any")
+	}
+	for i := 0; i < len(p.EXT_X_I_FRAME_STREAM_INF); i++ {
+		s := &p.EXT_X_I_FRAME_STREAM_INF[i]
+		tag := m3u8.TagEXT_X_I_FRAME_STREAM_INF
+		v.validateBandwidth(s.Line, tag, s.BANDWIDTH, s.AVERAGE_BANDWIDTH)
+		v.validateVariant(s.Line, tag, s.SCORE, s.VIDEO_RANGE, s.REQ_VIDEO_LAYOUT, s.STABLE_VARIANT_ID)
+		if s.URI == "" {
+			v.add(Error, s.Line, tag, "missing URI")
+		}
+		if s.CODECS == "" {
+			v.add(Warning, s.Line, tag, "should include CODECS")
+		}
+		v.validateGroupReference(groups, s.Line, tag, "VIDEO", s.VIDEO)
+	}
+	// RFC8216 4.3.4.4，DATA-ID:LANGUAGE
+	sessionData := make(map[string]bool)
+	for i := 0; i < len(p.EXT_X_SESSION_DATA); i++ {
+		s := &p.EXT_X_SESSION_DATA[i]
+		tag := m3u8.TagEXT_X_SESSION_DATA
+		if s.DATA_ID == "" {
+			v.add(Error, s.Line, tag, "missing DATA-ID")
+		}
+		if (s.VALUE == "") == (s.URI == "") {
+			v.add(Error, s.Line, tag, "must contain either VALUE or URI")
+		}
+		k := s.DATA_ID + ":" + s.LANGUAGE
+		if sessionData[k] {
+			v.add(Error, s.Line, tag, "DATA-ID '%s' with LANGUAGE '%s' already exists", s.DATA_ID, s.LANGUAGE)
+		}
+		sessionData[k] = true
+	}
+	if p.EXT_X_SESSION_KEY != nil {
+		v.validateEXT_X_KEY(p.EXT_X_SESSION_KEY, m3u8.TagEXT_X_SESSION_KEY)
+	}
+	v.validateEXT_X_START(p.EXT_X_START)
+	v.validateContentSteering(p)
+	return v.findings
+}
+
+// draft-pantos-hls-rfc8216bis 4.4.6.6
+func (v *validator) validateContentSteering(p *m3u8.MasterPlayList) {
+	for i := 0; i < len(p.EXT_X_STREAM_INF); i++ {
+		s := &p.EXT_X_STREAM_INF[i]
+		if s.PATHWAY_ID != "" && !m3u8.IsPathwayID(s.PATHWAY_ID) {
+			v.add(Error, s.Line, m3u8.TagEXT_X_STREAM_INF, "invalid PATHWAY-ID '%s'", s.PATHWAY_ID)
+		}
+	}
+	for i := 0; i < len(p.EXT_X_I_FRAME_STREAM_INF); i++ {
+		s := &p.EXT_X_I_FRAME_STREAM_INF[i]
+		if s.PATHWAY_ID != "" && !m3u8.IsPathwayID(s.PATHWAY_ID) {
+			v.add(Error, s.Line, m3u8.TagEXT_X_I_FRAME_STREAM_INF, "invalid PATHWAY-ID '%s'", s.PATHWAY_ID)
+		}
+	}
+	c := p.EXT_X_CONTENT_STEERING
+	if c == nil {
+		return
+	}
+	tag := m3u8.TagEXT_X_CONTENT_STEERING
+	if c.SERVER_URI == "" {
+		v.add(Error, c.Line, tag, "missing SERVER-URI")
+	}
+	if c.PATHWAY_ID != "" {
+		if !m3u8.IsPathwayID(c.PATHWAY_ID) {
+			v.add(Error, c.Line, tag, "invalid PATHWAY-ID '%s'", c.PATHWAY_ID)
+		}
+		if len(p.PathwayVariants(c.PATHWAY_ID)) < 1 {
+			v.add(Error, c.Line, tag, "PATHWAY-ID '%s' does not match any %s", c.PATHWAY_ID, m3u8.TagEXT_X_STREAM_INF)
+		}
+	}
+}
+
+// RFC8216 4.3.4.1
+func (v *validator) validateEXT_X_MEDIA(m *m3u8.EXT_X_MEDIA) {
+	tag := m3u8.TagEXT_X_MEDIA
+	switch m.TYPE {
+	case "AUDIO", "VIDEO", "SUBTITLES":
+		if m.INSTREAM_ID != "" {
+			v.add(Error, m.Line, tag, "INSTREAM-ID is only allowed when TYPE is CLOSED-CAPTIONS")
+		}
+		if m.TYPE == "SUBTITLES" && m.URI == "" {
+			v.add(Error, m.Line, tag, "URI is required when TYPE is SUBTITLES")
+		}
+	case "CLOSED-CAPTIONS":
+		if m.URI != "" {
+			v.add(Error, m.Line, tag, "URI is not allowed when TYPE is CLOSED-CAPTIONS")
+		}
+		if m.INSTREAM_ID == "" {
+			v.add(Error, m.Line, tag, "missing INSTREAM-ID")
+		}
+	default:
+		v.add(Error, m.Line, tag, "invalid TYPE '%s'", m.TYPE)
+	}
+	if m.GROUP_ID == "" {
+		v.add(Error, m.Line, tag, "missing GROUP-ID")
+	}
+	if m.NAME == "" {
+		v.add(Error, m.Line, tag, "missing NAME")
+	}
+	if m.FORCED && m.TYPE != "SUBTITLES" {
+		v.add(Error, m.Line, tag, "FORCED is only allowed when TYPE is SUBTITLES")
+	}
+	// draft-pantos-hls-rfc8216bis 4.4.6.1
+	if (m.BIT_DEPTH != 0 || m.SAMPLE_RATE != 0) && m.TYPE != "AUDIO" {
+		v.add(Error, m.Line, tag, "BIT-DEPTH and SAMPLE-RATE are only allowed when TYPE is AUDIO")
+	}
+	if m.STABLE_RENDITION_ID != "" && !isStableID(m.STABLE_RENDITION_ID) {
+		v.add(Error, m.Line, tag, "invalid STABLE-RENDITION-ID '%s'", m.STABLE_RENDITION_ID)
+	}
+}
+
+// 已知的REQ-VIDEO-LAYOUT
+var videoLayouts = map[string]bool{
+	"CH-STEREO": true,
+	"CH-MONO":   true,
+	"PROJ-RECT": true,
+	"PROJ-EQUI": true,
+	"PROJ-HEQU": true,
+	"PROJ-PRIM": true,
+	"PROJ-AIV":  true,
+}
+
+// 检查STREAM-INF和I-FRAME-STREAM-INF新增的属性，draft-pantos-hls-rfc8216bis 4.4.6.2
+func (v *validator) validateVariant(line int, tag string, score float64, videoRange, videoLayout, stableID string) {
+	if score < 0 {
+		v.add(Error, line, tag, "SCORE must not be negative")
+	}
+	switch videoRange {
+	case "", "SDR", "HLG", "PQ":
+	default:
+		v.add(Error, line, tag, "invalid VIDEO-RANGE '%s', must be SDR|HLG|PQ", videoRange)
+	}
+	if videoLayout != "" {
+		for _, s := range strings.Split(videoLayout, ",") {
+			if !videoLayouts[s] {
+				v.add(Warning, line, tag, "unknown REQ-VIDEO-LAYOUT '%s'", s)
+			}
+		}
+	}
+	if stableID != "" && !isStableID(stableID) {
+		v.add(Error, line, tag, "invalid STABLE-VARIANT-ID '%s'", stableID)
+	}
+}
+
+// STABLE-VARIANT-ID和STABLE-RENDITION-ID只能是[a-z]，[A-Z]，[0-9]，'+'，'/'，'='，'.'，'-'和'_'
+func isStableID(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') &&
+			strings.IndexByte("+/=.-_", c) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 检查一组EXT-X-MEDIA，RFC8216 4.3.4.1.1
+func (v *validator) validateGroup(group []*m3u8.EXT_X_MEDIA) {
+	tag := m3u8.TagEXT_X_MEDIA
+	names := make(map[string]bool)
+	defaults := 0
+	for _, m := range group {
+		if names[m.NAME] {
+			v.add(Error, m.Line, tag, "NAME '%s' already exists in GROUP-ID '%s'", m.NAME, m.GROUP_ID)
+		}
+		names[m.NAME] = true
+		if m.DEFAULT {
+			defaults++
+			if defaults > 1 {
+				v.add(Error, m.Line, tag, "more than one DEFAULT=YES in GROUP-ID '%s'", m.GROUP_ID)
+			}
+		}
+	}
+	// RFC8216没有要求必须有DEFAULT=YES，只是客户端没有选择时使用它
+	if defaults == 0 {
+		m := group[0]
+		v.add(Warning, m.Line, tag, "no DEFAULT=YES in GROUP-ID '%s'", m.GROUP_ID)
+	}
+}
+
+// STREAM-INF引用的GROUP-ID必须存在，RFC8216 4.3.4.2
+func (v *validator) validateGroupReference(groups map[string][]*m3u8.EXT_X_MEDIA, line int, tag, typ, groupID string) {
+	if groupID == "" {
+		return
+	}
+	if _, ok := groups[typ+":"+groupID]; !ok {
+		v.add(Error, line, tag, "%s GROUP-ID '%s' does not match any %s with TYPE %s", typ, groupID, m3u8.TagEXT_X_MEDIA, typ)
+	}
+}
+
+// RFC8216 4.3.4.2
+func (v *validator) validateBandwidth(line int, tag string, bandwidth, average int64) {
+	if bandwidth < 1 {
+		v.add(Error, line, tag, "missing BANDWIDTH")
+	}
+	if average > bandwidth {
+		v.add(Warning, line, tag, "AVERAGE-BANDWIDTH %d is greater than BANDWIDTH %d", average, bandwidth)
+	}
+}
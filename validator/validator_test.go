@@ -0,0 +1,217 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	m3u8 "github.com/qq51529210/m3u8"
+)
+
+// 解析src，宽松模式，出错时结束测试
+func decode(t *testing.T, src string) m3u8.PlayList {
+	t.Helper()
+	p, err := m3u8.NewDecoder(strings.NewReader(src), m3u8.Lenient).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range []struct {
+		src  string
+		want []string
+	}{
+		{
+			"#EXTM3U\n" +
+				"#EXT-X-VERSION:2\n" +
+				"#EXT-X-TARGETDURATION:10\n" +
+				"#EXT-X-KEY:METHOD=AES-128,URI=\"k\",KEYFORMAT=\"x\"\n" +
+				"#EXTINF:9.5,\n" +
+				"a.ts\n" +
+				"#EXT-X-BYTERANGE:100\n" +
+				"#EXTINF:9,\n" +
+				"b.ts\n",
+			[]string{
+				"error line 6: #EXTINF floating-point duration requires #EXT-X-VERSION 3, but it is 2",
+				"error line 4: #EXT-X-KEY KEYFORMAT and KEYFORMATVERSIONS attributes requires #EXT-X-VERSION 5, but it is 2",
+				"error line 9: #EXT-X-BYTERANGE requires #EXT-X-VERSION 4, but it is 2",
+				"error line 9: #EXT-X-BYTERANGE <o> is not present, but previous segment is not a sub-range of the same resource",
+			},
+		},
+		{
+			"#EXTM3U\n" +
+				"#EXT-X-TARGETDURATION:10\n" +
+				"#EXT-X-DATERANGE:ID=\"a\",START-DATE=\"2020-01-01T00:00:00Z\"\n" +
+				"#EXTINF:10,\n" +
+				"a.ts\n",
+			[]string{
+				"error line 3: #EXT-X-DATERANGE requires at least one #EXT-X-PROGRAM-DATE-TIME",
+			},
+		},
+		{
+			"#EXTM3U\n" +
+				"#EXT-X-VERSION:9\n" +
+				"#EXT-X-TARGETDURATION:4\n" +
+				"#EXT-X-PART-INF:PART-TARGET=0.5\n" +
+				"#EXTINF:4,\n" +
+				"a.mp4\n" +
+				"#EXT-X-PART:URI=\"p0.mp4\",DURATION=0.8\n",
+			[]string{
+				"error line 7: #EXT-X-PART DURATION 0.8 is greater than PART-TARGET 0.5",
+				"error line 4: #EXT-X-SERVER-CONTROL PART-HOLD-BACK is required when #EXT-X-PART-INF is present",
+			},
+		},
+		{
+			"#EXTM3U\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"a\",NAME=\"x\",DEFAULT=YES\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"a\",NAME=\"z\",DEFAULT=YES,AUTOSELECT=YES\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"b\",NAME=\"y\"\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=100,AVERAGE-BANDWIDTH=200,AUDIO=\"c\"\n" +
+				"v.m3u8\n",
+			[]string{
+				"error line 3: #EXT-X-MEDIA more than one DEFAULT=YES in GROUP-ID 'a'",
+				"warning line 4: #EXT-X-MEDIA no DEFAULT=YES in GROUP-ID 'b'",
+				"warning line 5: #EXT-X-STREAM-INF AVERAGE-BANDWIDTH 200 is greater than BANDWIDTH 100",
+				"warning line 5: #EXT-X-STREAM-INF should include CODECS",
+				"error line 5: #EXT-X-STREAM-INF AUDIO GROUP-ID 'c' does not match any #EXT-X-MEDIA with TYPE AUDIO",
+			},
+		},
+		{
+			"#EXTM3U\n" +
+				"#EXT-X-CONTENT-STEERING:SERVER-URI=\"/steering\",PATHWAY-ID=\"CDN-C\"\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=100,CODECS=\"avc1.4d401f\",PATHWAY-ID=\"CDN-A\"\n" +
+				"a.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=100,CODECS=\"avc1.4d401f\",PATHWAY-ID=\"CDN B\"\n" +
+				"b.m3u8\n",
+			[]string{
+				"error line 5: #EXT-X-STREAM-INF invalid PATHWAY-ID 'CDN B'",
+				"error line 2: #EXT-X-CONTENT-STEERING PATHWAY-ID 'CDN-C' does not match any #EXT-X-STREAM-INF",
+			},
+		},
+		{
+			"#EXTM3U\n" +
+				"#EXT-X-VERSION:3\n" +
+				"#EXT-X-TARGETDURATION:10\n" +
+				"#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00.000Z\n" +
+				"#EXT-X-DATERANGE:ID=\"a\",CLASS=\"com.apple.hls.interstitial\",START-DATE=\"2020-01-01T00:00:00.000Z\"," +
+				"CUE=\"PRE,POST,X\",X-ASSET-URI=\"a.m3u8\",X-ASSET-LIST=\"a.json\"\n" +
+				"#EXT-X-DATERANGE:ID=\"b\",CLASS=\"com.apple.hls.interstitial\",START-DATE=\"2020-01-01T00:00:00.000Z\"," +
+				"X-PLAYOUT-LIMIT=0,X-SNAP=\"OUT,NEXT\",X-RESTRICT=\"SKIP,SEEK\"\n" +
+				"#EXTINF:10,\n" +
+				"a.ts\n",
+			[]string{
+				"error line 5: #EXT-X-DATERANGE invalid CUE 'X', must be PRE|POST|ONCE",
+				"error line 5: #EXT-X-DATERANGE CUE must not contain both PRE and POST",
+				"error line 5: #EXT-X-DATERANGE interstitial must contain either X-ASSET-URI or X-ASSET-LIST",
+				"error line 6: #EXT-X-DATERANGE interstitial must contain either X-ASSET-URI or X-ASSET-LIST",
+				"error line 6: #EXT-X-DATERANGE X-PLAYOUT-LIMIT must be positive",
+				"error line 6: #EXT-X-DATERANGE invalid X-SNAP 'NEXT', must be OUT|IN",
+				"error line 6: #EXT-X-DATERANGE invalid X-RESTRICT 'SEEK', must be SKIP|JUMP",
+			},
+		},
+		{
+			"#EXTM3U\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"a\",NAME=\"a\",DEFAULT=YES,BIT-DEPTH=16,SAMPLE-RATE=48000,STABLE-RENDITION-ID=\"a.1\"\n" +
+				"#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"s\",NAME=\"s\",DEFAULT=YES,URI=\"s.m3u8\",SAMPLE-RATE=48000,STABLE-RENDITION-ID=\"s 1\"\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=100,CODECS=\"avc1.4d401f\",AUDIO=\"a\",SUBTITLES=\"s\",SCORE=2,VIDEO-RANGE=PQ," +
+				"REQ-VIDEO-LAYOUT=\"CH-STEREO,CH-MONO\",STABLE-VARIANT-ID=\"v+1/=_-\"\n" +
+				"a.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=100,CODECS=\"avc1.4d401f\",VIDEO-RANGE=SDR,REQ-VIDEO-LAYOUT=\"CH-3D\",STABLE-VARIANT-ID=\"v#1\"\n" +
+				"b.m3u8\n" +
+				"#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=10,CODECS=\"avc1.4d401f\",URI=\"i.m3u8\",VIDEO-RANGE=HLG,STABLE-VARIANT-ID=\"i:1\"\n",
+			[]string{
+				"error line 3: #EXT-X-MEDIA BIT-DEPTH and SAMPLE-RATE are only allowed when TYPE is AUDIO",
+				"error line 3: #EXT-X-MEDIA invalid STABLE-RENDITION-ID 's 1'",
+				"warning line 6: #EXT-X-STREAM-INF unknown REQ-VIDEO-LAYOUT 'CH-3D'",
+				"error line 6: #EXT-X-STREAM-INF invalid STABLE-VARIANT-ID 'v#1'",
+				"warning line 6: #EXT-X-STREAM-INF SCORE should be present on every variant if it is present on any",
+				"error line 8: #EXT-X-I-FRAME-STREAM-INF invalid STABLE-VARIANT-ID 'i:1'",
+			},
+		},
+	} {
+		findings := Validate(decode(t, c.src))
+		if len(findings) != len(c.want) {
+			t.Fatalf("got %v, want %v", findings, c.want)
+		}
+		for i, f := range findings {
+			if f.String() != c.want[i] {
+				t.Fatalf("finding %d: got %q, want %q", i, f.String(), c.want[i])
+			}
+		}
+		if !HasError(findings) {
+			t.Fatal("HasError() got false")
+		}
+	}
+}
+
+// 解析时同一个KEYFORMAT的key会替换前面的，只有自己构造的列表会有这个问题
+func TestValidateKeys(t *testing.T) {
+	k1 := &m3u8.EXT_X_KEY{METHOD: "AES-128", URI: "a"}
+	k2 := &m3u8.EXT_X_KEY{METHOD: "AES-128", URI: "b", KEYFORMAT: m3u8.DefaultKeyFormat}
+	p := &m3u8.MediaPlayList{
+		EXT_X_VERSION:        5,
+		EXT_X_TARGETDURATION: 4,
+		MediaSegment: []m3u8.MediaSegment{
+			{EXTINF: m3u8.EXTINF{DURATION: 4}, URI: "a.ts", EXT_X_KEY: []*m3u8.EXT_X_KEY{k1, k2}},
+			{EXTINF: m3u8.EXTINF{DURATION: 4}, URI: "b.ts", EXT_X_KEY: []*m3u8.EXT_X_KEY{k1, k2}},
+		},
+	}
+	findings := ValidateMedia(p)
+	// 第二个片段的key已经检查过了
+	want := "error: #EXT-X-KEY more than one KEYFORMAT 'identity' in a segment"
+	if len(findings) != 1 || findings[0].String() != want {
+		t.Fatalf("got %v, want %s", findings, want)
+	}
+}
+
+// 负数的SCORE和错误的VIDEO-RANGE解析时就会返回错误，只有自己构造的列表会有这个问题
+func TestValidateVariant(t *testing.T) {
+	p := &m3u8.MasterPlayList{
+		EXT_X_STREAM_INF: []m3u8.EXT_X_STREAM_INF{
+			{BANDWIDTH: 100, CODECS: "avc1.4d401f", URI: "a.m3u8", SCORE: -1, VIDEO_RANGE: "HDR"},
+		},
+		EXT_X_I_FRAME_STREAM_INF: []m3u8.EXT_X_I_FRAME_STREAM_INF{
+			{BANDWIDTH: 10, CODECS: "avc1.4d401f", URI: "i.m3u8", SCORE: -1, VIDEO_RANGE: "sdr"},
+		},
+	}
+	want := []string{
+		"error: #EXT-X-STREAM-INF SCORE must not be negative",
+		"error: #EXT-X-STREAM-INF invalid VIDEO-RANGE 'HDR', must be SDR|HLG|PQ",
+		"error: #EXT-X-I-FRAME-STREAM-INF SCORE must not be negative",
+		"error: #EXT-X-I-FRAME-STREAM-INF invalid VIDEO-RANGE 'sdr', must be SDR|HLG|PQ",
+	}
+	findings := ValidateMaster(p)
+	if len(findings) != len(want) {
+		t.Fatalf("got %v, want %v", findings, want)
+	}
+	for i, f := range findings {
+		if f.String() != want[i] {
+			t.Fatalf("finding %d: got %q, want %q", i, f.String(), want[i])
+		}
+	}
+}
+
+// testdata中的列表都是正确的
+func TestValidateTestData(t *testing.T) {
+	files, err := filepath.Glob("../testdata/*.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := m3u8.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if findings := Validate(p); len(findings) != 0 {
+			t.Fatalf("%s: %v", file, findings)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package m3u8
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 以下是属性值类型的解析和格式化函数，
+// 也可以用于把以前版本的string字段转换成现在的类型。
+
+// <date-time-msec>的格式，ISO/IEC 8601:2004
+const DateTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// <decimal-resolution>，<width>x<height>
+type Resolution struct {
+	Width  int64
+	Height int64
+}
+
+// 解析<width>x<height>
+func ParseResolution(s string) (Resolution, error) {
+	var r Resolution
+	i := strings.IndexByte(s, 'x')
+	if i < 0 {
+		return r, fmt.Errorf("invalid decimal-resolution '%s'", s)
+	}
+	var err error
+	r.Width, err = strconv.ParseInt(s[:i], 10, 64)
+	if err != nil || r.Width < 0 {
+		return r, fmt.Errorf("invalid decimal-resolution '%s'", s)
+	}
+	r.Height, err = strconv.ParseInt(s[i+1:], 10, 64)
+	if err != nil || r.Height < 0 {
+		return r, fmt.Errorf("invalid decimal-resolution '%s'", s)
+	}
+	return r, nil
+}
+
+// 格式化为<width>x<height>
+func (r Resolution) String() string {
+	return strconv.FormatInt(r.Width, 10) + "x" + strconv.FormatInt(r.Height, 10)
+}
+
+// 实现encoding.TextMarshaler
+func (r Resolution) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// 实现encoding.TextUnmarshaler
+func (r *Resolution) UnmarshalText(b []byte) error {
+	var err error
+	*r, err = ParseResolution(string(b))
+	return err
+}
+
+// 是否没有设置
+func (r Resolution) IsZero() bool {
+	return r.Width == 0 && r.Height == 0
+}
+
+// 解析<n>[@<o>]
+func ParseByteRange(s string) (*EXT_X_BYTERANGE, error) {
+	tag := new(EXT_X_BYTERANGE)
+	n := s
+	i := strings.IndexByte(s, '@')
+	if i >= 0 {
+		n = s[:i]
+	}
+	var err error
+	tag.N, err = ParseDecimalInteger(n)
+	if err != nil {
+		return nil, err
+	}
+	if i >= 0 {
+		o, err := ParseDecimalInteger(s[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		tag.O = &o
+	}
+	return tag, nil
+}
+
+// 格式化为<n>[@<o>]
+func (b *EXT_X_BYTERANGE) String() string {
+	s := strconv.FormatInt(b.N, 10)
+	if b.O != nil {
+		s += "@" + strconv.FormatInt(*b.O, 10)
+	}
+	return s
+}
+
+// 实现encoding.TextMarshaler
+func (b *EXT_X_BYTERANGE) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// 实现encoding.TextUnmarshaler
+func (b *EXT_X_BYTERANGE) UnmarshalText(text []byte) error {
+	p, err := ParseByteRange(string(text))
+	if err != nil {
+		return err
+	}
+	*b = *p
+	return nil
+}
+
+// 解析<decimal-integer>
+func ParseDecimalInteger(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid decimal-integer '%s'", s)
+	}
+	return n, nil
+}
+
+// 格式化为<decimal-integer>
+func FormatDecimalInteger(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// 解析<decimal-floating-point>，NaN，Inf和负数都是错误
+func ParseDecimalFloat(s string) (float64, error) {
+	f, err := ParseSignedDecimalFloat(s)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid decimal-floating-point '%s'", s)
+	}
+	return f, nil
+}
+
+// 解析<signed-decimal-floating-point>，NaN和Inf是错误
+func ParseSignedDecimalFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid signed-decimal-floating-point '%s'", s)
+	}
+	return f, nil
+}
+
+// 格式化为<decimal-floating-point>，使用最少的位数
+func FormatDecimalFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// 解析<hexadecimal-sequence>，0x或者0X开头
+func ParseHex(s string) ([]byte, error) {
+	if !hasHexPrefix(s) {
+		return nil, fmt.Errorf("invalid hexadecimal-sequence '%s'", s)
+	}
+	h := s[2:]
+	if len(h)%2 != 0 {
+		h = "0" + h
+	}
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hexadecimal-sequence '%s'", s)
+	}
+	return b, nil
+}
+
+// 格式化为<hexadecimal-sequence>
+func FormatHex(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+// 解析YES/NO
+func ParseBool(s string) (bool, error) {
+	switch s {
+	case "YES":
+		return true, nil
+	case "NO":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid enumerated-string '%s', must be YES or NO", s)
+	}
+}
+
+// 格式化为YES/NO
+func FormatBool(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
+
+// 解析<date-time-msec>
+func ParseDateTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid date-time '%s'", s)
+	}
+	return t, nil
+}
+
+// 格式化为<date-time-msec>
+func FormatDateTime(t time.Time) string {
+	return t.Format(DateTimeFormat)
+}
+
+// s是否以0x或者0X开头
+func hasHexPrefix(s string) bool {
+	return len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+}
@@ -0,0 +1,115 @@
+package m3u8
+
+import "math"
+
+// 列表中使用的功能需要的最低版本，RFC8216 7
+type VersionRequirement struct {
+	Version int64
+	Tag     string // 功能相关的tag
+	Feature string // 功能的说明，空表示tag本身
+	Line    int    // 解析时的行号，0表示不知道
+}
+
+// 返回媒体列表中需要版本2以上的功能，
+// 同一个#EXT-X-KEY和#EXT-X-MAP只返回一次
+func (p *MediaPlayList) VersionRequirements() []VersionRequirement {
+	var r []VersionRequirement
+	if p.EXT_X_I_FRAMES_ONLY {
+		r = append(r, VersionRequirement{Version: 4, Tag: TagEXT_X_I_FRAMES_ONLY, Line: p.Lines[TagEXT_X_I_FRAMES_ONLY]})
+	}
+	r = appendDefineVersionRequirements(r, p.EXT_X_DEFINE)
+	if p.EXT_X_SKIP != nil {
+		r = append(r, VersionRequirement{Version: 9, Tag: TagEXT_X_SKIP, Line: p.EXT_X_SKIP.Line})
+	}
+	var keys []*EXT_X_KEY
+	var xmap *EXT_X_MAP
+	for i := 0; i < len(p.MediaSegment); i++ {
+		s := &p.MediaSegment[i]
+		d := s.EXTINF.DURATION
+		if d != math.Trunc(d) {
+			r = append(r, VersionRequirement{Version: 3, Tag: TagEXTINF, Feature: "floating-point duration", Line: s.Line})
+		}
+		if s.EXT_X_BYTERANGE != nil {
+			r = append(r, VersionRequirement{Version: 4, Tag: TagEXT_X_BYTERANGE, Line: s.Line})
+		}
+		for _, k := range s.EXT_X_KEY {
+			if !containsKey(keys, k) {
+				r = k.appendVersionRequirements(r, TagEXT_X_KEY)
+			}
+		}
+		keys = s.EXT_X_KEY
+		if s.EXT_X_MAP != nil && s.EXT_X_MAP != xmap {
+			if p.EXT_X_I_FRAMES_ONLY {
+				r = append(r, VersionRequirement{Version: 5, Tag: TagEXT_X_MAP, Line: s.EXT_X_MAP.Line})
+			} else {
+				r = append(r, VersionRequirement{
+					Version: 6,
+					Tag:     TagEXT_X_MAP,
+					Feature: "without " + TagEXT_X_I_FRAMES_ONLY,
+					Line:    s.EXT_X_MAP.Line,
+				})
+			}
+		}
+		xmap = s.EXT_X_MAP
+	}
+	return r
+}
+
+// 返回主列表中需要版本2以上的功能
+func (p *MasterPlayList) VersionRequirements() []VersionRequirement {
+	var r []VersionRequirement
+	for i := 0; i < len(p.EXT_X_MEDIA); i++ {
+		m := &p.EXT_X_MEDIA[i]
+		if m.TYPE == "CLOSED-CAPTIONS" && len(m.INSTREAM_ID) > 7 && m.INSTREAM_ID[:7] == "SERVICE" {
+			r = append(r, VersionRequirement{Version: 7, Tag: TagEXT_X_MEDIA, Feature: "INSTREAM-ID " + m.INSTREAM_ID, Line: m.Line})
+		}
+	}
+	r = appendDefineVersionRequirements(r, p.EXT_X_DEFINE)
+	if p.EXT_X_SESSION_KEY != nil {
+		r = p.EXT_X_SESSION_KEY.appendVersionRequirements(r, TagEXT_X_SESSION_KEY)
+	}
+	return r
+}
+
+// 添加#EXT-X-DEFINE需要的版本，NAME和IMPORT是8，QUERYPARAM是11
+func appendDefineVersionRequirements(r []VersionRequirement, defines []EXT_X_DEFINE) []VersionRequirement {
+	for i := 0; i < len(defines); i++ {
+		if defines[i].QUERYPARAM != "" {
+			r = append(r, VersionRequirement{Version: 11, Tag: TagEXT_X_DEFINE, Feature: "QUERYPARAM attribute", Line: defines[i].Line})
+			continue
+		}
+		r = append(r, VersionRequirement{Version: 8, Tag: TagEXT_X_DEFINE, Line: defines[i].Line})
+	}
+	return r
+}
+
+// 添加#EXT-X-KEY和#EXT-X-SESSION-KEY的属性需要的版本
+func (k *EXT_X_KEY) appendVersionRequirements(r []VersionRequirement, tag string) []VersionRequirement {
+	if len(k.IV) > 0 {
+		r = append(r, VersionRequirement{Version: 2, Tag: tag, Feature: "IV attribute", Line: k.Line})
+	}
+	if k.KEYFORMAT != "" || k.KEYFORMATVERSIONS != "" {
+		r = append(r, VersionRequirement{Version: 5, Tag: tag, Feature: "KEYFORMAT and KEYFORMATVERSIONS attributes", Line: k.Line})
+	}
+	return r
+}
+
+// 返回兼容列表中所有功能的最低版本，最小是1
+func (p *MediaPlayList) MinimumVersion() int64 {
+	return minimumVersion(p.VersionRequirements())
+}
+
+// 返回兼容列表中所有功能的最低版本，最小是1
+func (p *MasterPlayList) MinimumVersion() int64 {
+	return minimumVersion(p.VersionRequirements())
+}
+
+func minimumVersion(r []VersionRequirement) int64 {
+	var v int64 = 1
+	for i := 0; i < len(r); i++ {
+		if r[i].Version > v {
+			v = r[i].Version
+		}
+	}
+	return v
+}
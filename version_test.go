@@ -0,0 +1,81 @@
+package m3u8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMediaPlayListMinimumVersion(t *testing.T) {
+	o := int64(0)
+	for _, c := range []struct {
+		name    string
+		segment MediaSegment
+		version int64
+	}{
+		{"integer duration", MediaSegment{EXTINF: EXTINF{DURATION: 10}}, 1},
+		{"IV", MediaSegment{EXT_X_KEY: []*EXT_X_KEY{{METHOD: "AES-128", URI: "k", IV: make([]byte, 16)}}}, 2},
+		{"floating-point duration", MediaSegment{EXTINF: EXTINF{DURATION: 9.5}}, 3},
+		{"byte range", MediaSegment{EXT_X_BYTERANGE: &EXT_X_BYTERANGE{N: 1, O: &o}}, 4},
+		{"KEYFORMAT", MediaSegment{EXT_X_KEY: []*EXT_X_KEY{{METHOD: "SAMPLE-AES", URI: "k", KEYFORMAT: "x"}}}, 5},
+		{"MAP", MediaSegment{EXT_X_MAP: &EXT_X_MAP{URI: "init.mp4"}}, 6},
+	} {
+		p := &MediaPlayList{MediaSegment: []MediaSegment{c.segment}}
+		if v := p.MinimumVersion(); v != c.version {
+			t.Fatalf("%s: got %d, want %d", c.name, v, c.version)
+		}
+	}
+	// #EXT-X-I-FRAMES-ONLY的#EXT-X-MAP是5
+	p := &MediaPlayList{EXT_X_I_FRAMES_ONLY: true, MediaSegment: []MediaSegment{{EXT_X_MAP: &EXT_X_MAP{URI: "init.mp4"}}}}
+	if v := p.MinimumVersion(); v != 5 {
+		t.Fatalf("I-FRAMES-ONLY MAP: got %d, want 5", v)
+	}
+	p = &MediaPlayList{EXT_X_DEFINE: []EXT_X_DEFINE{{NAME: "a", VALUE: "b"}}}
+	if v := p.MinimumVersion(); v != 8 {
+		t.Fatalf("DEFINE: got %d, want 8", v)
+	}
+	p = &MediaPlayList{EXT_X_SKIP: &EXT_X_SKIP{SKIPPED_SEGMENTS: 1}}
+	if v := p.MinimumVersion(); v != 9 {
+		t.Fatalf("SKIP: got %d, want 9", v)
+	}
+}
+
+func TestMasterPlayListMinimumVersion(t *testing.T) {
+	p := &MasterPlayList{EXT_X_MEDIA: []EXT_X_MEDIA{{TYPE: "CLOSED-CAPTIONS", GROUP_ID: "cc", NAME: "cc", INSTREAM_ID: "CC1"}}}
+	if v := p.MinimumVersion(); v != 1 {
+		t.Fatalf("INSTREAM-ID CC1: got %d, want 1", v)
+	}
+	p.EXT_X_MEDIA[0].INSTREAM_ID = "SERVICE1"
+	if v := p.MinimumVersion(); v != 7 {
+		t.Fatalf("INSTREAM-ID SERVICE1: got %d, want 7", v)
+	}
+}
+
+func TestEncoderAutoVersion(t *testing.T) {
+	p := &MediaPlayList{
+		EXT_X_VERSION:        2,
+		EXT_X_TARGETDURATION: 10,
+		MediaSegment:         []MediaSegment{{EXTINF: EXTINF{DURATION: 9.5}, URI: "a.ts"}},
+	}
+	var b strings.Builder
+	e := NewEncoder(&b)
+	e.SetAutoVersion(true)
+	if err := e.Encode(p); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "#EXTM3U\n#EXT-X-VERSION:3\n") {
+		t.Fatalf("got\n%s", b.String())
+	}
+	// 列表本身不会被修改
+	if p.EXT_X_VERSION != 2 {
+		t.Fatalf("EXT_X_VERSION is modified to %d", p.EXT_X_VERSION)
+	}
+	// 版本1不输出
+	p = &MediaPlayList{EXT_X_TARGETDURATION: 10, MediaSegment: []MediaSegment{{EXTINF: EXTINF{DURATION: 10}, URI: "a.ts"}}}
+	b.Reset()
+	if err := e.Encode(p); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), TagEXT_X_VERSION) {
+		t.Fatalf("got\n%s", b.String())
+	}
+}
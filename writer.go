@@ -1,10 +1,19 @@
 package m3u8
 
-import "io"
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// 没有任何属性的<attribute-list>，RFC8216没有这种格式
+var errEmptyAttributeList = errors.New("empty attribute-list")
 
 type Writer struct {
-	buff   []byte    // 缓存
-	writer io.Writer // 输出目标
+	buff    []byte    // 缓存
+	writer  io.Writer // 输出目标
+	written int64     // 累计输出的字节数
 }
 
 // writer是接收输出的数据
@@ -23,6 +32,7 @@ func (w *Writer) SetWriter(writer io.Writer) {
 func (w *Writer) flushBuffer() (int, error) {
 	n, err := w.writer.Write(w.buff)
 	w.buff = w.buff[:0]
+	w.written += int64(n)
 	return n, err
 }
 
@@ -33,12 +43,21 @@ func (w *Writer) writeNoEmptyString(s string) {
 	}
 }
 
-// s有数据才添加到缓存buff，先添加a，在添加s
-func (w *Writer) writeAttributeNoEmptyString(a, s string) {
-	if s != "" {
-		w.buff = append(w.buff, a...)
-		w.buff = append(w.buff, s...)
+// 添加<attribute-list>和换行，然后输出
+func (w *Writer) writeAttributeList(v interface{}) (int, error) {
+	n := len(w.buff)
+	var err error
+	w.buff, err = appendAttributes(w.buff, v)
+	if err == nil && len(w.buff) == n {
+		err = errEmptyAttributeList
 	}
+	if err != nil {
+		w.buff = w.buff[:0]
+		return 0, err
+	}
+	w.buff = append(w.buff, '\n')
+	// 输出
+	return w.flushBuffer()
 }
 
 // #EXTM3U
@@ -49,9 +68,9 @@ func (w *Writer) EXTM3U() (int, error) {
 }
 
 // #EXT-X-VERSION:<n>
-func (w *Writer) EXT_X_VERSION(tag string) (int, error) {
+func (w *Writer) EXT_X_VERSION(tag int64) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-VERSION:"...)
-	w.buff = append(w.buff, tag...)
+	w.buff = strconv.AppendInt(w.buff, tag, 10)
 	w.buff = append(w.buff, '\n')
 	// 输出
 	return w.flushBuffer()
@@ -61,7 +80,7 @@ func (w *Writer) EXT_X_VERSION(tag string) (int, error) {
 func (w *Writer) EXTINF(tag *EXTINF) (int, error) {
 	w.buff = append(w.buff, "#EXTINF:"...)
 	// duration
-	w.buff = append(w.buff, tag.DURATION...)
+	w.buff = strconv.AppendFloat(w.buff, tag.DURATION, 'f', -1, 64)
 	w.buff = append(w.buff, ',')
 	// title
 	w.writeNoEmptyString(tag.TITLE)
@@ -74,13 +93,8 @@ func (w *Writer) EXTINF(tag *EXTINF) (int, error) {
 // #EXT-X-BYTERANGE:<n>[@<o>]
 func (w *Writer) EXT_X_BYTERANGE(tag *EXT_X_BYTERANGE) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-BYTERANGE:"...)
-	// n
-	w.buff = append(w.buff, tag.N...)
-	// o
-	if tag.O != "" {
-		w.buff = append(w.buff, '@')
-		w.buff = append(w.buff, tag.O...)
-	}
+	// n[@o]
+	w.buff = append(w.buff, tag.String()...)
 	// 换行
 	w.buff = append(w.buff, '\n')
 	// 输出
@@ -97,39 +111,19 @@ func (w *Writer) EXT_X_DISCONTINUITY() (int, error) {
 // #EXT-X-KEY:<attribute-list>
 func (w *Writer) EXT_X_KEY(tag *EXT_X_KEY) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-KEY:"...)
-	// METHOD
-	w.writeAttributeNoEmptyString("METHOD=", tag.METHOD)
-	// URI
-	w.writeAttributeNoEmptyString(",URI=", tag.URI)
-	// IV
-	w.writeAttributeNoEmptyString(",IV=", tag.IV)
-	// KEYFORMAT
-	w.writeAttributeNoEmptyString(",KEYFORMAT=", tag.KEYFORMAT)
-	// KEYFORMATVERSIONS
-	w.writeAttributeNoEmptyString(",KEYFORMATVERSIONS=", tag.KEYFORMATVERSIONS)
-	// 换行
-	w.buff = append(w.buff, '\n')
-	// 输出
-	return w.flushBuffer()
+	return w.writeAttributeList(tag)
 }
 
 // #EXT-X-MAP:<attribute-list>
 func (w *Writer) EXT_X_MAP(tag *EXT_X_MAP) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-MAP:"...)
-	// URI
-	w.writeAttributeNoEmptyString("URI=", tag.URI)
-	// BYTERANGE
-	w.writeAttributeNoEmptyString(",BYTERANGE=", tag.BYTERANGE)
-	// 换行
-	w.buff = append(w.buff, '\n')
-	// 输出
-	return w.flushBuffer()
+	return w.writeAttributeList(tag)
 }
 
 // #EXT-X-PROGRAM-DATE-TIME:<date-time-msec>
-func (w *Writer) EXT_X_PROGRAM_DATE_TIME(dateTime string) (int, error) {
+func (w *Writer) EXT_X_PROGRAM_DATE_TIME(dateTime time.Time) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-PROGRAM-DATE-TIME:"...)
-	w.buff = append(w.buff, dateTime...)
+	w.buff = dateTime.AppendFormat(w.buff, DateTimeFormat)
 	// 换行
 	w.buff = append(w.buff, '\n')
 	// 输出
@@ -139,60 +133,47 @@ func (w *Writer) EXT_X_PROGRAM_DATE_TIME(dateTime string) (int, error) {
 // #EXT-X-DATERANGE:<attribute-list>
 func (w *Writer) EXT_X_DATERANGE(tag *EXT_X_DATERANGE) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-DATERANGE:"...)
-	// ID
-	w.writeAttributeNoEmptyString("ID=", tag.ID)
-	// CLASS
-	w.writeAttributeNoEmptyString(",CLASS=", tag.CLASS)
-	// START_DATE
-	w.writeAttributeNoEmptyString(",START_DATE=", tag.START_DATE)
-	// END_DATE
-	w.writeAttributeNoEmptyString(",END_DATE=", tag.END_DATE)
-	// DURATION
-	w.writeAttributeNoEmptyString(",DURATION=", tag.DURATION)
-	// PLANNED_DURATION
-	w.writeAttributeNoEmptyString(",PLANNED_DURATION=", tag.PLANNED_DURATION)
-	for k, v := range tag.X_CLIENT_ATTRIBUTE {
-		w.buff = append(w.buff, ',')
-		w.buff = append(w.buff, k...)
-		w.buff = append(w.buff, '=')
-		w.buff = append(w.buff, v...)
-	}
-	// SCTE35_CMD
-	w.writeAttributeNoEmptyString(",SCTE35_CMD=", tag.SCTE35_CMD)
-	// SCTE35_OUT
-	w.writeAttributeNoEmptyString(",SCTE35_OUT=", tag.SCTE35_OUT)
-	// SCTE35_IN
-	w.writeAttributeNoEmptyString(",SCTE35_IN=", tag.SCTE35_IN)
-	// END_ON_NEXT
-	w.writeAttributeNoEmptyString(",END_ON_NEXT=", tag.END_ON_NEXT)
-	// 换行
+	return w.writeAttributeList(tag)
+}
+
+// #EXT-X-GAP
+func (w *Writer) EXT_X_GAP() (int, error) {
+	w.buff = append(w.buff, "#EXT-X-GAP\n"...)
+	// 输出
+	return w.flushBuffer()
+}
+
+// #EXT-X-BITRATE:<rate>
+func (w *Writer) EXT_X_BITRATE(tag int64) (int, error) {
+	w.buff = append(w.buff, "#EXT-X-BITRATE:"...)
+	w.buff = strconv.AppendInt(w.buff, tag, 10)
 	w.buff = append(w.buff, '\n')
 	// 输出
 	return w.flushBuffer()
 }
 
 // #EXT-X-TARGETDURATION:<s>
-func (w *Writer) EXT_X_TARGETDURATION(tag string) (int, error) {
+func (w *Writer) EXT_X_TARGETDURATION(tag int64) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-TARGETDURATION:"...)
-	w.buff = append(w.buff, tag...)
+	w.buff = strconv.AppendInt(w.buff, tag, 10)
 	w.buff = append(w.buff, '\n')
 	// 输出
 	return w.flushBuffer()
 }
 
 // #EXT-X-MEDIA-SEQUENCE:<s>
-func (w *Writer) EXT_X_MEDIA_SEQUENCE(tag string) (int, error) {
+func (w *Writer) EXT_X_MEDIA_SEQUENCE(tag int64) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-MEDIA-SEQUENCE:"...)
-	w.buff = append(w.buff, tag...)
+	w.buff = strconv.AppendInt(w.buff, tag, 10)
 	w.buff = append(w.buff, '\n')
 	// 输出
 	return w.flushBuffer()
 }
 
 // #EXT-X-DISCONTINUITY-SEQUENCE:<number>
-func (w *Writer) EXT_X_DISCONTINUITY_SEQUENCE(tag string) (int, error) {
+func (w *Writer) EXT_X_DISCONTINUITY_SEQUENCE(tag int64) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-DISCONTINUITY-SEQUENCE:"...)
-	w.buff = append(w.buff, tag...)
+	w.buff = strconv.AppendInt(w.buff, tag, 10)
 	w.buff = append(w.buff, '\n')
 	// 输出
 	return w.flushBuffer()
@@ -224,128 +205,42 @@ func (w *Writer) EXT_X_I_FRAMES_ONLY() (int, error) {
 // #EXT-X-MEDIA:<attribute-list>
 func (w *Writer) EXT_X_MEDIA(tag *EXT_X_MEDIA) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-MEDIA:"...)
-	// TYPE
-	w.writeAttributeNoEmptyString("TYPE=", tag.TYPE)
-	// URI
-	w.writeAttributeNoEmptyString(",URI=", tag.URI)
-	// GROUP_ID
-	w.writeAttributeNoEmptyString(",GROUP_ID=", tag.GROUP_ID)
-	// LANGUAGE
-	w.writeAttributeNoEmptyString(",LANGUAGE=", tag.LANGUAGE)
-	// ASSOC_LANGUAGE
-	w.writeAttributeNoEmptyString(",ASSOC_LANGUAGE=", tag.ASSOC_LANGUAGE)
-	// NAME
-	w.writeAttributeNoEmptyString(",NAME=", tag.NAME)
-	// DEFAULT
-	w.writeAttributeNoEmptyString(",DEFAULT=", tag.DEFAULT)
-	// AUTOSELECT
-	w.writeAttributeNoEmptyString(",AUTOSELECT=", tag.AUTOSELECT)
-	// FORCED
-	w.writeAttributeNoEmptyString(",FORCED=", tag.FORCED)
-	// INSTREAM_ID
-	w.writeAttributeNoEmptyString(",INSTREAM_ID=", tag.INSTREAM_ID)
-	// CHARACTERISTICS
-	w.writeAttributeNoEmptyString(",CHARACTERISTICS=", tag.CHARACTERISTICS)
-	// CHANNELS
-	w.writeAttributeNoEmptyString(",CHANNELS=", tag.CHANNELS)
-	// 换行
-	w.buff = append(w.buff, '\n')
-	// 输出
-	return w.flushBuffer()
+	return w.writeAttributeList(tag)
 }
 
 // #EXT-X-STREAM-INF:<attribute-list>
 // <URI>
 func (w *Writer) EXT_X_STREAM_INF(tag *EXT_X_STREAM_INF) (int, error) {
+	n, err := w.streamInf(tag)
+	if err != nil {
+		return n, err
+	}
+	m, err := w.URI(tag.URI)
+	return n + m, err
+}
+
+// 只输出#EXT-X-STREAM-INF这一行，不输出URI
+func (w *Writer) streamInf(tag *EXT_X_STREAM_INF) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-STREAM-INF:"...)
-	// BANDWIDTH
-	w.writeAttributeNoEmptyString("BANDWIDTH=", tag.BANDWIDTH)
-	// AVERAGE_BANDWIDTH
-	w.writeAttributeNoEmptyString(",AVERAGE_BANDWIDTH=", tag.AVERAGE_BANDWIDTH)
-	// CODECS
-	w.writeAttributeNoEmptyString(",CODECS=", tag.CODECS)
-	// RESOLUTION
-	w.writeAttributeNoEmptyString(",RESOLUTION=", tag.RESOLUTION)
-	// FRAME_RATE
-	w.writeAttributeNoEmptyString(",FRAME_RATE=", tag.FRAME_RATE)
-	// HDCP_LEVEL
-	w.writeAttributeNoEmptyString(",HDCP_LEVEL=", tag.HDCP_LEVEL)
-	// AUDIO
-	w.writeAttributeNoEmptyString(",AUDIO=", tag.AUDIO)
-	// VIDEO
-	w.writeAttributeNoEmptyString(",VIDEO=", tag.VIDEO)
-	// SUBTITLES
-	w.writeAttributeNoEmptyString(",SUBTITLES=", tag.SUBTITLES)
-	// CLOSED_CAPTIONS
-	w.writeAttributeNoEmptyString(",CLOSED_CAPTIONS=", tag.CLOSED_CAPTIONS)
-	// 换行
-	w.buff = append(w.buff, '\n')
-	// URI
-	w.buff = append(w.buff, tag.URI...)
-	w.buff = append(w.buff, '\n')
-	// 输出
-	return w.flushBuffer()
+	return w.writeAttributeList(tag)
 }
 
 // #EXT-X-I-FRAME-STREAM-INF:<attribute-list>
-// <URI>
 func (w *Writer) EXT_X_I_FRAME_STREAM_INF(tag *EXT_X_I_FRAME_STREAM_INF) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-I-FRAME-STREAM-INF:"...)
-	// BANDWIDTH
-	w.writeAttributeNoEmptyString("BANDWIDTH=", tag.BANDWIDTH)
-	// AVERAGE_BANDWIDTH
-	w.writeAttributeNoEmptyString(",AVERAGE_BANDWIDTH=", tag.AVERAGE_BANDWIDTH)
-	// CODECS
-	w.writeAttributeNoEmptyString(",CODECS=", tag.CODECS)
-	// RESOLUTION
-	w.writeAttributeNoEmptyString(",RESOLUTION=", tag.RESOLUTION)
-	// HDCP_LEVEL
-	w.writeAttributeNoEmptyString(",HDCP_LEVEL=", tag.HDCP_LEVEL)
-	// VIDEO
-	w.writeAttributeNoEmptyString(",VIDEO=", tag.VIDEO)
-	// 换行
-	w.buff = append(w.buff, '\n')
-	// URI
-	w.buff = append(w.buff, tag.URI...)
-	w.buff = append(w.buff, '\n')
-	// 输出
-	return w.flushBuffer()
+	return w.writeAttributeList(tag)
 }
 
 // #EXT-X-SESSION-DATA:<attribute-list>
 func (w *Writer) EXT_X_SESSION_DATA(tag *EXT_X_SESSION_DATA) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-SESSION-DATA:"...)
-	// DATA_ID
-	w.writeAttributeNoEmptyString("DATA_ID=", tag.DATA_ID)
-	// VALUE
-	w.writeAttributeNoEmptyString(",VALUE=", tag.VALUE)
-	// URI
-	w.writeAttributeNoEmptyString(",URI=", tag.URI)
-	// LANGUAGE
-	w.writeAttributeNoEmptyString(",LANGUAGE=", tag.LANGUAGE)
-	// 换行
-	w.buff = append(w.buff, '\n')
-	// 输出
-	return w.flushBuffer()
+	return w.writeAttributeList(tag)
 }
 
 // #EXT-X-SESSION-KEY:<attribute-list>
 func (w *Writer) EXT_X_SESSION_KEY(tag *EXT_X_KEY) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-SESSION-KEY:"...)
-	// METHOD
-	w.writeAttributeNoEmptyString("METHOD=", tag.METHOD)
-	// URI
-	w.writeAttributeNoEmptyString(",URI=", tag.URI)
-	// IV
-	w.writeAttributeNoEmptyString(",IV=", tag.IV)
-	// KEYFORMAT
-	w.writeAttributeNoEmptyString(",KEYFORMAT=", tag.KEYFORMAT)
-	// KEYFORMATVERSIONS
-	w.writeAttributeNoEmptyString(",KEYFORMATVERSIONS=", tag.KEYFORMATVERSIONS)
-	// 换行
-	w.buff = append(w.buff, '\n')
-	// 输出
-	return w.flushBuffer()
+	return w.writeAttributeList(tag)
 }
 
 // #EXT-X-INDEPENDENT-SEGMENTS
@@ -355,15 +250,91 @@ func (w *Writer) EXT_X_INDEPENDENT_SEGMENTS() (int, error) {
 	return w.flushBuffer()
 }
 
+// #EXT-X-DEFINE:<attribute-list>
+func (w *Writer) EXT_X_DEFINE(tag *EXT_X_DEFINE) (int, error) {
+	w.buff = append(w.buff, "#EXT-X-DEFINE:"...)
+	return w.writeAttributeList(tag)
+}
+
 // #EXT-X-START:<attribute-list>
 func (w *Writer) EXT_X_START(tag *EXT_X_START) (int, error) {
 	w.buff = append(w.buff, "#EXT-X-START:"...)
-	// TIME_OFFSET
-	w.writeAttributeNoEmptyString("TIME_OFFSET=", tag.TIME_OFFSET)
-	// PRECISE
-	w.writeAttributeNoEmptyString(",PRECISE=", tag.PRECISE)
-	// 换行
+	return w.writeAttributeList(tag)
+}
+
+// #EXT-X-CONTENT-STEERING:<attribute-list>
+func (w *Writer) EXT_X_CONTENT_STEERING(tag *EXT_X_CONTENT_STEERING) (int, error) {
+	w.buff = append(w.buff, "#EXT-X-CONTENT-STEERING:"...)
+	return w.writeAttributeList(tag)
+}
+
+// #EXT-X-PART:<attribute-list>
+func (w *Writer) EXT_X_PART(tag *EXT_X_PART) (int, error) {
+	w.buff = append(w.buff, "#EXT-X-PART:"...)
+	return w.writeAttributeList(tag)
+}
+
+// #EXT-X-PART-INF:<attribute-list>
+func (w *Writer) EXT_X_PART_INF(tag *EXT_X_PART_INF) (int, error) {
+	w.buff = append(w.buff, "#EXT-X-PART-INF:"...)
+	return w.writeAttributeList(tag)
+}
+
+// #EXT-X-SERVER-CONTROL:<attribute-list>
+func (w *Writer) EXT_X_SERVER_CONTROL(tag *EXT_X_SERVER_CONTROL) (int, error) {
+	w.buff = append(w.buff, "#EXT-X-SERVER-CONTROL:"...)
+	return w.writeAttributeList(tag)
+}
+
+// #EXT-X-PRELOAD-HINT:<attribute-list>
+func (w *Writer) EXT_X_PRELOAD_HINT(tag *EXT_X_PRELOAD_HINT) (int, error) {
+	w.buff = append(w.buff, "#EXT-X-PRELOAD-HINT:"...)
+	return w.writeAttributeList(tag)
+}
+
+// #EXT-X-RENDITION-REPORT:<attribute-list>
+func (w *Writer) EXT_X_RENDITION_REPORT(tag *EXT_X_RENDITION_REPORT) (int, error) {
+	w.buff = append(w.buff, "#EXT-X-RENDITION-REPORT:"...)
+	return w.writeAttributeList(tag)
+}
+
+// #EXT-X-SKIP:<attribute-list>
+func (w *Writer) EXT_X_SKIP(tag *EXT_X_SKIP) (int, error) {
+	w.buff = append(w.buff, "#EXT-X-SKIP:"...)
+	return w.writeAttributeList(tag)
+}
+
+// <URI>
+func (w *Writer) URI(uri string) (int, error) {
+	w.buff = append(w.buff, uri...)
+	w.buff = append(w.buff, '\n')
+	// 输出
+	return w.flushBuffer()
+}
+
+// 原样输出一行，用于不认识的tag和注释
+func (w *Writer) RawLine(line string) (int, error) {
+	w.buff = append(w.buff, line...)
 	w.buff = append(w.buff, '\n')
 	// 输出
 	return w.flushBuffer()
 }
+
+// #<tag>[:<value>]，用于自定义的tag，value是空只输出tag
+func (w *Writer) Tag(tag, value string) (int, error) {
+	w.buff = append(w.buff, tag...)
+	if value != "" {
+		w.buff = append(w.buff, ':')
+		w.buff = append(w.buff, value...)
+	}
+	w.buff = append(w.buff, '\n')
+	// 输出
+	return w.flushBuffer()
+}
+
+// #<tag>:<attribute-list>，用于自定义的tag，v是带有m3u8 struct tag的struct或者AttributeList
+func (w *Writer) AttributeList(tag string, v interface{}) (int, error) {
+	w.buff = append(w.buff, tag...)
+	w.buff = append(w.buff, ':')
+	return w.writeAttributeList(v)
+}